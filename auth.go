@@ -0,0 +1,195 @@
+package opensky
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTokenURL = "https://auth.opensky-network.org/auth/realms/opensky-network/protocol/openid-connect/token"
+
+	// Tokens are refreshed this long before they actually expire, to account for clock skew and latency.
+	// The margin is reduced to half the lifetime for short-lived tokens.
+	tokenRefreshMargin = 30 * time.Second
+	// Lifetime assumed for tokens whose response contains no positive expires_in.
+	defaultTokenLifetime = 5 * time.Minute
+)
+
+// Authenticator adds credentials to every request sent by a Client.
+//
+// Implementations must be safe for concurrent use, since a Client may be shared between goroutines.
+type Authenticator interface {
+	// Authenticate sets the credentials on the passed request, e.g. via an Authorization header.
+	// The context of the request should be used for any I/O the authenticator performs.
+	Authenticate(request *http.Request) error
+}
+
+// An Authenticator using HTTP basic authentication with username and password.
+type BasicAuthenticator struct {
+	username string
+	password string
+}
+
+// Creates a new authenticator for HTTP basic authentication.
+func NewBasicAuthenticator(username string, password string) *BasicAuthenticator {
+	return &BasicAuthenticator{
+		username: username,
+		password: password,
+	}
+}
+
+// Sets the basic authentication header on the request.
+func (a *BasicAuthenticator) Authenticate(request *http.Request) error {
+	request.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+// An Authenticator using the OAuth2 client credentials flow.
+//
+// Bearer tokens are fetched lazily from the token endpoint, cached and refreshed shortly before
+// they expire. The authenticator is safe for concurrent use; concurrent requests share a single
+// token fetch, which is not bound to the context of any of them. Every request waits for the fetch
+// only until its own context is done.
+type ClientCredentialsAuthenticator struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	httpClient   *http.Client
+	now          func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time   // Time from which the cached token is replaced by a new one.
+	fetch     *tokenFetch // Token fetch in progress, if any.
+}
+
+// A single token fetch, shared by all requests waiting for a token.
+// The token and err fields may only be read after done is closed.
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// Raw response of the OAuth2 token endpoint.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Creates a new authenticator for the OAuth2 client credentials flow.
+// The tokenURL and httpClient parameters are optional: if empty or nil, the OpenSky token endpoint
+// and an HTTP client with a 30 second timeout are used.
+func NewClientCredentialsAuthenticator(clientID string, clientSecret string, tokenURL string, httpClient *http.Client) *ClientCredentialsAuthenticator {
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &ClientCredentialsAuthenticator{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenURL:     tokenURL,
+		httpClient:   httpClient,
+		now:          time.Now,
+	}
+}
+
+// Sets the bearer token header on the request, fetching a new token first if needed.
+func (a *ClientCredentialsAuthenticator) Authenticate(request *http.Request) error {
+	token, err := a.getToken(request)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Returns the cached token, or fetches a new one if there is none or it is about to expire.
+// Waits for the fetch until the context of the request is done.
+func (a *ClientCredentialsAuthenticator) getToken(request *http.Request) (string, error) {
+	a.mu.Lock()
+	if a.token != "" && a.now().Before(a.refreshAt) {
+		token := a.token
+		a.mu.Unlock()
+		return token, nil
+	}
+	fetch := a.fetch
+	if fetch == nil {
+		fetch = &tokenFetch{done: make(chan struct{})}
+		a.fetch = fetch
+		go a.fetchToken(fetch)
+	}
+	a.mu.Unlock()
+	select {
+	case <-fetch.done:
+		return fetch.token, fetch.err
+	case <-request.Context().Done():
+		return "", fmt.Errorf("couldn't fetch access token: %w", request.Context().Err())
+	}
+}
+
+// Performs the passed fetch, caches its token on success and signals all waiting requests.
+func (a *ClientCredentialsAuthenticator) fetchToken(fetch *tokenFetch) {
+	token, lifetime, err := a.requestToken()
+	a.mu.Lock()
+	if err == nil {
+		margin := tokenRefreshMargin
+		if margin > lifetime/2 {
+			margin = lifetime / 2
+		}
+		a.token = token
+		a.refreshAt = a.now().Add(lifetime - margin)
+	}
+	a.fetch = nil
+	a.mu.Unlock()
+	fetch.token, fetch.err = token, err
+	close(fetch.done)
+}
+
+// Requests a new token from the token endpoint, and returns it with its lifetime.
+// The request is bound to the timeout of the HTTP client only, since it is shared by all requests
+// waiting for a token.
+func (a *ClientCredentialsAuthenticator) requestToken() (string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+	tokenRequest, err := http.NewRequest("POST", a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	tokenRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := a.httpClient.Do(tokenRequest)
+	if err != nil {
+		return "", 0, fmt.Errorf("couldn't fetch access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("couldn't fetch access token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("couldn't fetch access token: %d: %v", resp.StatusCode, string(body))
+	}
+	var token tokenResponse
+	if err = json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("couldn't parse access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("couldn't parse access token: response contains no access_token")
+	}
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	return token.AccessToken, lifetime, nil
+}
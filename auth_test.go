@@ -0,0 +1,214 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a stand-in for the OAuth2 token endpoint, which issues numbered tokens.
+func newTokenServer(t *testing.T, expiresIn int64, fetches *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.NoError(t, r.ParseForm())
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("client_id") != "my-client" || r.PostForm.Get("client_secret") != "my-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		n := atomic.AddInt32(fetches, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://localhost", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, NewBasicAuthenticator("user", "pass").Authenticate(request))
+	username, password, ok := request.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+}
+
+func TestNewClientAuthenticator(t *testing.T) {
+	assert.Nil(t, NewClient("", "").auth)
+	assert.Nil(t, NewClient("user", "").auth)
+	assert.Nil(t, NewClient("", "pass").auth)
+	assert.Equal(t, NewBasicAuthenticator("user", "pass"), NewClient("user", "pass").auth)
+	assert.Nil(t, NewClient("user", "pass", WithAuthenticator(nil)).auth)
+}
+
+func TestClientCredentialsAuthenticator(t *testing.T) {
+	var fetches int32
+	tokenServer := newTokenServer(t, 300, &fetches)
+	defer tokenServer.Close()
+	now := time.Unix(1624891429, 0)
+	auth := NewClientCredentialsAuthenticator("my-client", "my-secret", tokenServer.URL, nil)
+	auth.now = func() time.Time { return now }
+	authenticate := func() string {
+		request, err := http.NewRequest("GET", "http://localhost", nil)
+		assert.NoError(t, err)
+		assert.NoError(t, auth.Authenticate(request))
+		return request.Header.Get("Authorization")
+	}
+	// First request fetches a token
+	assert.Equal(t, "Bearer token-1", authenticate())
+	// Token is cached while valid
+	now = now.Add(4 * time.Minute)
+	assert.Equal(t, "Bearer token-1", authenticate())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+	// Token is refreshed shortly before it expires
+	now = now.Add(31 * time.Second)
+	assert.Equal(t, "Bearer token-2", authenticate())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestClientCredentialsAuthenticatorLifetime(t *testing.T) {
+	type testCase struct {
+		expiresIn       string        // Raw expires_in field of the token response, omitted if empty.
+		expectedRefresh time.Duration // Time after which the token is refreshed.
+	}
+	cases := []testCase{
+		// Missing or invalid lifetimes fall back to the default
+		{"", 270 * time.Second},
+		{`,"expires_in":0`, 270 * time.Second},
+		{`,"expires_in":-10`, 270 * time.Second},
+		// The margin is at most half the lifetime
+		{`,"expires_in":20`, 10 * time.Second},
+		{`,"expires_in":1`, 500 * time.Millisecond},
+	}
+	for _, c := range cases {
+		var fetches int32
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&fetches, 1)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer"%s}`, n, c.expiresIn)
+		}))
+		now := time.Unix(1624891429, 0)
+		auth := NewClientCredentialsAuthenticator("my-client", "my-secret", tokenServer.URL, nil)
+		auth.now = func() time.Time { return now }
+		authenticate := func() string {
+			request, err := http.NewRequest("GET", "http://localhost", nil)
+			assert.NoError(t, err)
+			assert.NoError(t, auth.Authenticate(request))
+			return request.Header.Get("Authorization")
+		}
+		assert.Equal(t, "Bearer token-1", authenticate(), c.expiresIn)
+		assert.Equal(t, "Bearer token-1", authenticate(), c.expiresIn)
+		now = now.Add(c.expectedRefresh - time.Millisecond)
+		assert.Equal(t, "Bearer token-1", authenticate(), c.expiresIn)
+		now = now.Add(time.Millisecond)
+		assert.Equal(t, "Bearer token-2", authenticate(), c.expiresIn)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&fetches), c.expiresIn)
+		tokenServer.Close()
+	}
+}
+
+func TestClientCredentialsAuthenticatorConcurrent(t *testing.T) {
+	var fetches int32
+	tokenServer := newTokenServer(t, 300, &fetches)
+	defer tokenServer.Close()
+	auth := NewClientCredentialsAuthenticator("my-client", "my-secret", tokenServer.URL, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request, err := http.NewRequest("GET", "http://localhost", nil)
+			assert.NoError(t, err)
+			assert.NoError(t, auth.Authenticate(request))
+			assert.Equal(t, "Bearer token-1", request.Header.Get("Authorization"))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestClientCredentialsAuthenticatorDeadline(t *testing.T) {
+	var fetches int32
+	release := make(chan struct{})
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		n := atomic.AddInt32(&fetches, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":300}`, n)
+	}))
+	defer tokenServer.Close()
+	auth := NewClientCredentialsAuthenticator("my-client", "my-secret", tokenServer.URL, nil)
+	// A slow token fetch is in progress
+	slow := make(chan error, 1)
+	go func() {
+		request, err := http.NewRequest("GET", "http://localhost", nil)
+		assert.NoError(t, err)
+		slow <- auth.Authenticate(request)
+	}()
+	// Other requests wait for the fetch only until their own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, "GET", "http://localhost", nil)
+	assert.NoError(t, err)
+	start := time.Now()
+	err = auth.Authenticate(request)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, "", request.Header.Get("Authorization"))
+	// The fetch completes for the other request, and is shared
+	close(release)
+	assert.NoError(t, <-slow)
+	request, err = http.NewRequest("GET", "http://localhost", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, auth.Authenticate(request))
+	assert.Equal(t, "Bearer token-1", request.Header.Get("Authorization"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestClientCredentialsAuthenticatorErrors(t *testing.T) {
+	var fetches int32
+	tokenServer := newTokenServer(t, 300, &fetches)
+	defer tokenServer.Close()
+	// Invalid credentials
+	auth := NewClientCredentialsAuthenticator("my-client", "wrong-secret", tokenServer.URL, nil)
+	request, err := http.NewRequest("GET", "http://localhost", nil)
+	assert.NoError(t, err)
+	assert.Error(t, auth.Authenticate(request))
+	assert.Equal(t, "", request.Header.Get("Authorization"))
+	// Malformed token response
+	badServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"token_type":"Bearer"}`))
+	}))
+	defer badServer.Close()
+	auth = NewClientCredentialsAuthenticator("my-client", "my-secret", badServer.URL, nil)
+	assert.Error(t, auth.Authenticate(request))
+}
+
+func TestClientWithClientCredentials(t *testing.T) {
+	var fetches int32
+	tokenServer := newTokenServer(t, 300, &fetches)
+	defer tokenServer.Close()
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+	}))
+	defer server.Close()
+	auth := NewClientCredentialsAuthenticator("my-client", "my-secret", tokenServer.URL, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithAuthenticator(auth))
+	_, err := client.GetOwnStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1"}, authorization)
+	// Token errors are returned before any API request is sent
+	failing := NewClientCredentialsAuthenticator("my-client", "wrong-secret", tokenServer.URL, nil)
+	client = NewClient("", "", WithBaseURL(server.URL), WithAuthenticator(failing))
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.Error(t, err)
+	assert.Len(t, authorization, 2)
+}
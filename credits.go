@@ -0,0 +1,135 @@
+package opensky
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Error returned by GetStates, if a query would exceed the daily credit budget of the client.
+var ErrCreditBudgetExceeded = errors.New("opensky: daily credit budget exceeded")
+
+// Returns the area of the bounding box in square degrees.
+// Boxes crossing the antimeridian are measured across it.
+func (b BoundingBox) Area() float64 {
+	width := b.LonMax - b.LonMin
+	if b.CrossesAntimeridian() {
+		width += 360
+	}
+	return math.Abs(b.LatMax-b.LatMin) * width
+}
+
+// Returns the API credits OpenSky charges for a GetStates query with the passed bounding box.
+//
+// The cost depends on the area of the bounding box:
+//   - up to 25 square degrees: 1 credit
+//   - up to 100 square degrees: 2 credits
+//   - up to 400 square degrees: 3 credits
+//   - more than 400 square degrees, or no bounding box at all: 4 credits
+//
+// Bounding boxes crossing the antimeridian are queried with two requests, whose costs are summed.
+func StatesCreditCost(bbox *BoundingBox) int {
+	if bbox == nil {
+		return 4
+	}
+	if bbox.CrossesAntimeridian() {
+		cost := 0
+		for _, part := range bbox.Split() {
+			cost += StatesCreditCost(&part)
+		}
+		return cost
+	}
+	area := bbox.Area()
+	switch {
+	case area <= 25:
+		return 1
+	case area <= 100:
+		return 2
+	case area <= 400:
+		return 3
+	}
+	return 4
+}
+
+// Client-side tracker for a daily API credit allowance.
+//
+// The budget is reset at midnight UTC. It is safe for concurrent use, hence it may be shared
+// between several clients using the same account.
+type CreditBudget struct {
+	limit int
+	warn  func(used int, cost int, limit int)
+	now   func() time.Time
+
+	mu   sync.Mutex
+	day  time.Time
+	used int
+}
+
+// Creates a new tracker for the passed daily credit allowance.
+//
+// If warn is nil, queries which would exceed the budget are refused with ErrCreditBudgetExceeded.
+// Otherwise, warn is invoked with the credits used so far, the cost of the query and the limit,
+// and the query is performed anyway.
+func NewCreditBudget(dailyLimit int, warn func(used int, cost int, limit int)) *CreditBudget {
+	return &CreditBudget{
+		limit: dailyLimit,
+		warn:  warn,
+		now:   time.Now,
+	}
+}
+
+// Returns the credits used today.
+func (b *CreditBudget) Used() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfNewDay()
+	return b.used
+}
+
+// Returns the credits left for today. Can be negative, if the budget only warns.
+func (b *CreditBudget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfNewDay()
+	return b.limit - b.used
+}
+
+// Charges the passed cost to the budget, or returns ErrCreditBudgetExceeded if the budget refuses it.
+func (b *CreditBudget) reserve(cost int) error {
+	b.mu.Lock()
+	b.resetIfNewDay()
+	used := b.used
+	exceeded := used+cost > b.limit
+	if exceeded && b.warn == nil {
+		b.mu.Unlock()
+		return ErrCreditBudgetExceeded
+	}
+	b.used += cost
+	b.mu.Unlock()
+	// The warning is issued without holding the lock, so that it may query the budget
+	if exceeded {
+		b.warn(used, cost, b.limit)
+	}
+	return nil
+}
+
+// Returns previously reserved credits to the budget, e.g. because the query failed.
+func (b *CreditBudget) refund(cost int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= cost
+	if b.used < 0 {
+		b.used = 0
+	}
+}
+
+// Resets the used credits, if the UTC day changed since the last charge.
+// Must be called with the lock held.
+func (b *CreditBudget) resetIfNewDay() {
+	day := b.now().UTC().Truncate(24 * time.Hour)
+	if !day.Equal(b.day) {
+		b.day = day
+		b.used = 0
+	}
+}
@@ -0,0 +1,112 @@
+package opensky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatesCreditCost(t *testing.T) {
+	type testCase struct {
+		bbox         *BoundingBox
+		expectedCost int
+	}
+	cases := []testCase{
+		{nil, 4},
+		{&BoundingBox{LatMin: 0, LonMin: 0, LatMax: 0, LonMax: 0}, 1},
+		{&BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}, 1},
+		{&BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10.1}, 2},
+		{&BoundingBox{LatMin: 40, LonMin: 0, LatMax: 50, LonMax: 10}, 2},
+		{&BoundingBox{LatMin: 40, LonMin: 0, LatMax: 50, LonMax: 40}, 3},
+		{&BoundingBox{LatMin: 30, LonMin: 0, LatMax: 50, LonMax: 20.5}, 4},
+		{&BoundingBox{LatMin: -90, LonMin: -180, LatMax: 90, LonMax: 180}, 4},
+		// Swapped latitudes result in the same area
+		{&BoundingBox{LatMin: 50, LonMin: 5, LatMax: 45, LonMax: 10}, 1},
+		// Boxes crossing the antimeridian are charged for both requests
+		{&BoundingBox{LatMin: 45, LonMin: 178, LatMax: 50, LonMax: -178}, 2},
+		{&BoundingBox{LatMin: -90, LonMin: 170, LatMax: 90, LonMax: -170}, 8},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expectedCost, StatesCreditCost(c.bbox), "%+v", c.bbox)
+	}
+}
+
+func TestCreditBudgetRefuse(t *testing.T) {
+	now := time.Date(2021, 6, 28, 23, 0, 0, 0, time.UTC)
+	budget := NewCreditBudget(10, nil)
+	budget.now = func() time.Time { return now }
+	assert.NoError(t, budget.reserve(4))
+	assert.NoError(t, budget.reserve(4))
+	assert.Equal(t, 8, budget.Used())
+	assert.Equal(t, 2, budget.Remaining())
+	assert.True(t, errors.Is(budget.reserve(3), ErrCreditBudgetExceeded))
+	assert.Equal(t, 8, budget.Used())
+	assert.NoError(t, budget.reserve(2))
+	assert.Equal(t, 0, budget.Remaining())
+	budget.refund(2)
+	assert.Equal(t, 2, budget.Remaining())
+	// Budget is reset at midnight UTC
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, 0, budget.Used())
+	assert.NoError(t, budget.reserve(4))
+	assert.Equal(t, 6, budget.Remaining())
+}
+
+func TestCreditBudgetWarn(t *testing.T) {
+	type warning struct {
+		used, cost, limit int
+	}
+	var warnings []warning
+	budget := NewCreditBudget(5, func(used int, cost int, limit int) {
+		warnings = append(warnings, warning{used, cost, limit})
+	})
+	assert.NoError(t, budget.reserve(4))
+	assert.Empty(t, warnings)
+	assert.NoError(t, budget.reserve(4))
+	assert.Equal(t, []warning{{4, 4, 5}}, warnings)
+	assert.Equal(t, 8, budget.Used())
+	assert.Equal(t, -3, budget.Remaining())
+}
+
+func TestClientCreditBudget(t *testing.T) {
+	requests := 0
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(6, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	// Global query costs 4 credits
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, budget.Remaining())
+	// Another global query is refused, without sending a request
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, ErrCreditBudgetExceeded))
+	assert.Equal(t, 1, requests)
+	// Own states are free
+	_, err = client.GetOwnStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, budget.Remaining())
+	// Failed queries are refunded
+	fail = true
+	bbox := &BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}
+	_, err = client.GetStates(time.Time{}, nil, bbox)
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, 2, budget.Remaining())
+	// Small queries still fit
+	fail = false
+	_, err = client.GetStates(time.Time{}, nil, bbox)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, budget.Remaining())
+}
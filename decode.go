@@ -0,0 +1,741 @@
+package opensky
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Maximum nesting depth of JSON values, identical to the limit of encoding/json.
+const maxDecodeDepth = 10000
+
+// Decodes a state vectors response in the JSON format returned by /states/all and /states/own,
+// without reflection and without intermediate interface{} values.
+//
+// The decoded states are appended to dst[:0], so that the states of a previous response may be
+// passed in to reuse their memory. Nullable fields of a state share a single allocation.
+//
+// The result is identical to the one of GetStates for the same response body, including the
+// handling of invalid values. Invalid state arrays are reported as *StateParseError, with the raw
+// JSON text of the offending value as json.RawMessage.
+func DecodeStatesResponse(data []byte, dst []State) (response GetStatesResponse, err error) {
+	return decodeStatesResponse(data, dst, time.Unix(0, 0))
+}
+
+// Shared implementation of DecodeStatesResponse and UnmarshalStatesResponse.
+// The defaultTime is used if the response time is null or absent.
+func decodeStatesResponse(data []byte, dst []State, defaultTime time.Time) (response GetStatesResponse, err error) {
+	p := statesDecoder{data: data, countries: map[string]string{}}
+	response.Time = defaultTime
+	response.States = dst[:0]
+	// Errors of invalid states are only reported after the whole input was checked for syntax
+	// errors, since later state arrays may replace earlier ones
+	var stateErr error
+	p.skipSpace()
+	switch p.peek() {
+	case '{':
+		stateErr, err = p.decodeResponse(&response)
+	case 'n':
+		err = p.expectLiteral("null")
+	default:
+		// Any other valid JSON value cannot be decoded into a response
+		if err = p.skipValue(); err == nil {
+			err = fmt.Errorf("invalid states response: expected object")
+		}
+	}
+	if err != nil {
+		return
+	}
+	p.skipSpace()
+	if p.pos < len(p.data) {
+		err = p.syntaxError("after top-level value")
+		return
+	}
+	err = stateErr
+	return
+}
+
+// Hand-written decoder for the state vectors JSON format.
+type statesDecoder struct {
+	data      []byte
+	pos       int
+	depth     int
+	countries map[string]string // Interned origin countries, since there are only a few distinct ones.
+}
+
+// Holds the nullable fields of a single state, so that they require only one allocation.
+type stateValues struct {
+	timePosition UnixTime
+	floats       [7]float64
+}
+
+// Indexes of the nullable float fields of a state array, in the order of stateValues.floats.
+var stateFloatIndexes = [...]int{5, 6, 7, 9, 10, 11, 13}
+
+// A JSON value scanned by the decoder. Arrays and objects are skipped and only reported by kind.
+type scannedValue struct {
+	kind    byte   // n (null), b (bool), 0 (number), s (string), [ (array) or { (object).
+	raw     []byte // Literal bytes of a number, or the content of a string without quotes.
+	escaped bool   // Whether a string contains escape sequences or non-ASCII bytes.
+	boolean bool   // Value of a bool.
+	start   int    // Offset of the value in the input.
+	end     int    // Offset after the value in the input.
+}
+
+// Decodes the top-level response object.
+// Returns the first error of an invalid state separately from syntax errors.
+func (p *statesDecoder) decodeResponse(response *GetStatesResponse) (stateErr error, err error) {
+	states := response.States
+	err = p.decodeObject(func(key string) error {
+		switch {
+		case strings.EqualFold(key, "time"):
+			return p.decodeTime(response)
+		case strings.EqualFold(key, "states"):
+			// Later states fields replace earlier ones
+			states = states[:0]
+			stateErr = nil
+			if p.peek() == 'n' {
+				return p.expectLiteral("null")
+			}
+			if p.peek() != '[' {
+				if err := p.skipValue(); err != nil {
+					return err
+				}
+				return fmt.Errorf("invalid states value: expected array")
+			}
+			i := 0
+			return p.decodeArray(func() error {
+				var state State
+				rowErr, err := p.decodeState(i, &state)
+				if err != nil {
+					return err
+				}
+				if rowErr != nil && stateErr == nil {
+					stateErr = rowErr
+				}
+				if stateErr == nil {
+					states = append(states, state)
+				}
+				i++
+				return nil
+			})
+		}
+		return p.skipValue()
+	})
+	if len(states) == 0 {
+		// Consistent with GetStates, which returns nil states for an empty response, unless dst was passed
+		states = response.States
+	}
+	response.States = states
+	return
+}
+
+// Decodes the time field of the response object.
+func (p *statesDecoder) decodeTime(response *GetStatesResponse) error {
+	v, err := p.scanValue()
+	if err != nil {
+		return err
+	}
+	switch v.kind {
+	case 'n':
+		return nil
+	case '0':
+		t, err := strconv.ParseInt(bytesToString(v.raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid time value: %s", v.raw)
+		}
+		response.Time = time.Unix(t, 0)
+		return nil
+	}
+	return fmt.Errorf("invalid time value: %s", p.data[v.start:v.end])
+}
+
+// Decodes a single state array, following the same rules as parseState.
+// Returns the first invalid field of the state separately from syntax errors.
+func (p *statesDecoder) decodeState(i int, state *State) (stateErr error, err error) {
+	if p.peek() != '[' {
+		var v scannedValue
+		if v, err = p.scanValue(); err != nil {
+			return
+		}
+		if v.kind != 'n' {
+			err = fmt.Errorf("invalid state value at position %d: expected array", i)
+			return
+		}
+		stateErr = &StateParseError{Index: i, Value: copyRaw(p.data[v.start:v.end]), Err: fmt.Errorf("response contains 0 values, expected 17 or 18")}
+		return
+	}
+	start := p.pos
+	var values *stateValues
+	fail := func(field string, v scannedValue, cause error) {
+		if stateErr == nil {
+			stateErr = &StateParseError{Index: i, Field: field, Value: copyRaw(p.data[v.start:v.end]), Err: cause}
+		}
+	}
+	n := 0
+	err = p.decodeArray(func() error {
+		idx := n
+		n++
+		if idx == 12 && p.peek() == '[' {
+			// sensors
+			sensors, sensorsErr, err := p.decodeSensors()
+			if err != nil {
+				return err
+			}
+			if sensorsErr != nil {
+				fail("sensors", scannedValue{start: sensorsErr.start, end: sensorsErr.end}, sensorsErr.err)
+			}
+			state.Sensors = sensors
+			return nil
+		}
+		v, err := p.scanValue()
+		if err != nil {
+			return err
+		}
+		switch idx {
+		case 0:
+			if v.kind != 's' {
+				fail("icao24", v, nil)
+				return nil
+			}
+			state.ICAO24 = p.stringValue(v)
+		case 1, 14:
+			var field *string
+			name := "callsign"
+			if idx == 1 {
+				field = &state.CallSign
+			} else {
+				field = &state.Squawk
+				name = "squawk"
+			}
+			switch v.kind {
+			case 'n':
+			case 's':
+				*field = p.stringValue(v)
+			default:
+				fail(name, v, nil)
+			}
+		case 2:
+			if v.kind != 's' {
+				fail("origin_country", v, nil)
+				return nil
+			}
+			state.OriginCountry = p.internCountry(v)
+		case 3:
+			if v.kind == 'n' {
+				return nil
+			}
+			t, err := scannedInt(v)
+			if err != nil {
+				fail("time_position", v, err)
+				return nil
+			}
+			if values == nil {
+				values = &stateValues{}
+			}
+			values.timePosition = newUnixTime(t)
+			state.TimePosition = &values.timePosition
+		case 4:
+			t, err := scannedInt(v)
+			if err != nil {
+				fail("last_contact", v, err)
+				return nil
+			}
+			state.LastContact = newUnixTime(t)
+		case 5, 6, 7, 9, 10, 11, 13:
+			if v.kind != '0' {
+				return nil
+			}
+			f, err := strconv.ParseFloat(bytesToString(v.raw), 64)
+			if err != nil {
+				return nil
+			}
+			if values == nil {
+				values = &stateValues{}
+			}
+			for k, fieldIdx := range stateFloatIndexes {
+				if fieldIdx == idx {
+					values.floats[k] = f
+					*stateFloatField(state, idx) = &values.floats[k]
+				}
+			}
+		case 8, 15:
+			if v.kind != 'b' {
+				if idx == 8 {
+					fail("on_ground", v, nil)
+				} else {
+					fail("spi", v, nil)
+				}
+				return nil
+			}
+			if idx == 8 {
+				state.OnGround = v.boolean
+			} else {
+				state.Spi = v.boolean
+			}
+		case 12:
+			if v.kind != 'n' {
+				fail("sensors", v, fmt.Errorf("couldn't parse %s as number array", p.data[v.start:v.end]))
+			}
+		case 16:
+			positionSource, err := scannedInt(v)
+			if err != nil {
+				fail("position_source", v, err)
+				return nil
+			}
+			state.PositionSource = PositionSource(positionSource)
+		case 17:
+			if v.kind == 'n' {
+				return nil
+			}
+			category, err := scannedInt(v)
+			if err != nil {
+				fail("category", v, err)
+				return nil
+			}
+			state.Category = AircraftCategory(category)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if n < 17 {
+		// The length check takes precedence over invalid fields
+		stateErr = &StateParseError{Index: i, Value: copyRaw(p.data[start:p.pos]), Err: fmt.Errorf("response contains %v values, expected 17 or 18", n)}
+	}
+	if stateErr != nil {
+		*state = State{}
+	}
+	return
+}
+
+// Describes an invalid element of a sensors array.
+type sensorsError struct {
+	start int
+	end   int
+	err   error
+}
+
+// Decodes a sensors array, which must only contain numbers.
+func (p *statesDecoder) decodeSensors() (sensors []int, sensorsErr *sensorsError, err error) {
+	start := p.pos
+	err = p.decodeArray(func() error {
+		v, err := p.scanValue()
+		if err != nil {
+			return err
+		}
+		if sensorsErr != nil {
+			return nil
+		}
+		sensor, err := scannedInt(v)
+		if err != nil {
+			sensors = nil
+			sensorsErr = &sensorsError{err: err}
+			return nil
+		}
+		sensors = append(sensors, int(sensor))
+		return nil
+	})
+	if sensorsErr != nil {
+		sensorsErr.start = start
+		sensorsErr.end = p.pos
+	}
+	return
+}
+
+// Returns a pointer to the nullable float field of a state with the passed index.
+func stateFloatField(state *State, idx int) **float64 {
+	switch idx {
+	case 5:
+		return &state.Longitude
+	case 6:
+		return &state.Latitude
+	case 7:
+		return &state.BarometricAltitude
+	case 9:
+		return &state.Velocity
+	case 10:
+		return &state.Heading
+	case 11:
+		return &state.VerticalRate
+	}
+	return &state.GeoAltitude
+}
+
+// Converts a scanned number to an int64, following the same rules as jsonNumberToInt.
+func scannedInt(v scannedValue) (int64, error) {
+	if v.kind != '0' {
+		return 0, fmt.Errorf("couldn't parse %s as number", v.raw)
+	}
+	s := bytesToString(v.raw)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't parse %s as number", v.raw)
+	}
+	return int64(f), nil
+}
+
+// Decodes an object, invoking fn for every key. fn must consume the value of the key.
+func (p *statesDecoder) decodeObject(fn func(key string) error) error {
+	if err := p.enter('{'); err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		p.depth--
+		return nil
+	}
+	for {
+		p.skipSpace()
+		if p.peek() != '"' {
+			return p.syntaxError("looking for beginning of object key string")
+		}
+		key, err := p.scanValue()
+		if err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.peek() != ':' {
+			return p.syntaxError("after object key")
+		}
+		p.pos++
+		p.skipSpace()
+		if err = fn(p.stringValue(key)); err != nil {
+			return err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.depth--
+			return nil
+		default:
+			return p.syntaxError("after object key:value pair")
+		}
+	}
+}
+
+// Decodes an array, invoking fn for every element. fn must consume the element.
+func (p *statesDecoder) decodeArray(fn func() error) error {
+	if err := p.enter('['); err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() == ']' {
+		p.pos++
+		p.depth--
+		return nil
+	}
+	for {
+		p.skipSpace()
+		if err := fn(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			p.depth--
+			return nil
+		default:
+			return p.syntaxError("after array element")
+		}
+	}
+}
+
+// Consumes the opening delimiter of an array or object.
+func (p *statesDecoder) enter(delim byte) error {
+	if p.peek() != delim {
+		return p.syntaxError("looking for beginning of value")
+	}
+	p.depth++
+	if p.depth > maxDecodeDepth {
+		return fmt.Errorf("invalid states response: exceeded max depth")
+	}
+	p.pos++
+	return nil
+}
+
+// Skips any JSON value, checking its syntax.
+func (p *statesDecoder) skipValue() error {
+	_, err := p.scanValue()
+	return err
+}
+
+// Scans the next JSON value. Arrays and objects are skipped.
+func (p *statesDecoder) scanValue() (v scannedValue, err error) {
+	p.skipSpace()
+	v.start = p.pos
+	switch c := p.peek(); {
+	case c == '"':
+		v.kind = 's'
+		v.raw, v.escaped, err = p.scanString()
+	case c == 't':
+		v.kind = 'b'
+		v.boolean = true
+		err = p.expectLiteral("true")
+	case c == 'f':
+		v.kind = 'b'
+		err = p.expectLiteral("false")
+	case c == 'n':
+		v.kind = 'n'
+		err = p.expectLiteral("null")
+	case c == '-' || (c >= '0' && c <= '9'):
+		v.kind = '0'
+		v.raw, err = p.scanNumber()
+	case c == '[':
+		v.kind = '['
+		err = p.decodeArray(p.skipValue)
+	case c == '{':
+		v.kind = '{'
+		err = p.decodeObject(func(string) error {
+			return p.skipValue()
+		})
+	default:
+		err = p.syntaxError("looking for beginning of value")
+	}
+	v.end = p.pos
+	return
+}
+
+// Scans a string, returning its content without quotes.
+func (p *statesDecoder) scanString() (raw []byte, escaped bool, err error) {
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case c == '"':
+			raw = p.data[start:p.pos]
+			p.pos++
+			return
+		case c == '\\':
+			escaped = true
+			p.pos++
+			switch p.peek() {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				p.pos++
+			case 'u':
+				p.pos++
+				for k := 0; k < 4; k++ {
+					if !isHex(p.peek()) {
+						err = p.syntaxError("in \\u hexadecimal character escape")
+						return
+					}
+					p.pos++
+				}
+			default:
+				err = p.syntaxError("in string escape code")
+				return
+			}
+		case c < ' ':
+			err = p.syntaxError("in string literal")
+			return
+		default:
+			if c >= utf8.RuneSelf {
+				escaped = true
+			}
+			p.pos++
+		}
+	}
+	err = p.syntaxError("in string literal")
+	return
+}
+
+// Scans a number, checking that it matches the JSON number grammar.
+func (p *statesDecoder) scanNumber() (raw []byte, err error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	switch c := p.peek(); {
+	case c == '0':
+		p.pos++
+	case c >= '1' && c <= '9':
+		p.skipDigits()
+	default:
+		return nil, p.syntaxError("in numeric literal")
+	}
+	if p.peek() == '.' {
+		p.pos++
+		if !isDigit(p.peek()) {
+			return nil, p.syntaxError("after decimal point in numeric literal")
+		}
+		p.skipDigits()
+	}
+	if c := p.peek(); c == 'e' || c == 'E' {
+		p.pos++
+		if c = p.peek(); c == '+' || c == '-' {
+			p.pos++
+		}
+		if !isDigit(p.peek()) {
+			return nil, p.syntaxError("in exponent of numeric literal")
+		}
+		p.skipDigits()
+	}
+	return p.data[start:p.pos], nil
+}
+
+// Consumes the passed literal, e.g. null.
+func (p *statesDecoder) expectLiteral(literal string) error {
+	if len(p.data)-p.pos < len(literal) || bytesToString(p.data[p.pos:p.pos+len(literal)]) != literal {
+		return p.syntaxError("in literal " + literal)
+	}
+	p.pos += len(literal)
+	return nil
+}
+
+func (p *statesDecoder) skipDigits() {
+	for isDigit(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *statesDecoder) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// Returns the next byte, or 0 at the end of the input.
+func (p *statesDecoder) peek() byte {
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+func (p *statesDecoder) syntaxError(context string) error {
+	if p.pos >= len(p.data) {
+		return fmt.Errorf("invalid states response: unexpected end of JSON input")
+	}
+	return fmt.Errorf("invalid states response: invalid character %q %s at offset %d", p.data[p.pos], context, p.pos)
+}
+
+// Returns the value of a scanned string.
+func (p *statesDecoder) stringValue(v scannedValue) string {
+	if !v.escaped {
+		return string(v.raw)
+	}
+	return unquote(v.raw)
+}
+
+// Returns the value of a scanned origin country, reusing previously allocated strings.
+func (p *statesDecoder) internCountry(v scannedValue) string {
+	if country, ok := p.countries[bytesToString(v.raw)]; ok && !v.escaped {
+		return country
+	}
+	country := p.stringValue(v)
+	if !v.escaped {
+		p.countries[country] = country
+	}
+	return country
+}
+
+// Decodes the content of a JSON string with escape sequences or non-ASCII characters.
+// Invalid UTF-8 and invalid surrogates are replaced by U+FFFD, like encoding/json does.
+func unquote(raw []byte) string {
+	b := make([]byte, 0, len(raw)+2*utf8.UTFMax)
+	for r := 0; r < len(raw); {
+		c := raw[r]
+		switch {
+		case c == '\\':
+			r++
+			switch raw[r] {
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'u':
+				rr := getu4(raw[r+1:])
+				r += 5
+				if utf16.IsSurrogate(rr) {
+					if r+1 < len(raw) && raw[r] == '\\' && raw[r+1] == 'u' {
+						if dec := utf16.DecodeRune(rr, getu4(raw[r+2:])); dec != unicode.ReplacementChar {
+							r += 6
+							b = appendRune(b, dec)
+							continue
+						}
+					}
+					rr = unicode.ReplacementChar
+				}
+				b = appendRune(b, rr)
+				continue
+			default:
+				b = append(b, raw[r])
+			}
+			r++
+		case c < utf8.RuneSelf:
+			b = append(b, c)
+			r++
+		default:
+			rr, size := utf8.DecodeRune(raw[r:])
+			r += size
+			b = appendRune(b, rr)
+		}
+	}
+	return string(b)
+}
+
+// Parses the 4 hex digits of a \u escape sequence.
+func getu4(s []byte) rune {
+	if len(s) < 4 {
+		return -1
+	}
+	r, err := strconv.ParseUint(bytesToString(s[:4]), 16, 64)
+	if err != nil {
+		return -1
+	}
+	return rune(r)
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// Returns a copy of the passed raw JSON text, so that it does not retain the input.
+func copyRaw(raw []byte) json.RawMessage {
+	return append(json.RawMessage(nil), raw...)
+}
+
+// Converts bytes to a string without copying.
+// The string must not be retained beyond the lifetime of the bytes.
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
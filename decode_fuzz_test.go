@@ -0,0 +1,18 @@
+//go:build go1.18
+// +build go1.18
+
+package opensky
+
+import (
+	"testing"
+)
+
+func FuzzDecodeStatesResponse(f *testing.F) {
+	f.Add(newStatesPayload(3))
+	f.Add([]byte(`{"time":1624958210,"states":[["ae1fa7","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,false,17.95,117.3,-1.3,[1,2,3],952.5,"0753",false,0,6]]}`))
+	f.Add([]byte(`{"States":null,"TIME":null}`))
+	f.Add([]byte(`{"states":[["é\ud800",null,"A",1.5,1e3,"x",null,null,true,null,null,null,["1"],null,null,false,0]]}`))
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		assertDecodeStatesEqual(t, payload)
+	})
+}
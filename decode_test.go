@@ -0,0 +1,159 @@
+package opensky
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Decodes the payload with both DecodeStatesResponse and the reflection-based decoding of GetStates,
+// and checks that the results are identical.
+func assertDecodeStatesEqual(t *testing.T, payload []byte) {
+	t.Helper()
+	response, err := DecodeStatesResponse(payload, nil)
+	var rawResponse unstructuredStateResponse
+	if rawErr := json.Unmarshal(payload, &rawResponse); rawErr != nil {
+		assert.Error(t, err, "%s", payload)
+		return
+	}
+	expected, expectedErr := parseStatesResponse(rawResponse, false)
+	if expectedErr != nil {
+		if assert.Error(t, err, "%s", payload) {
+			var expectedParseErr, parseErr *StateParseError
+			assert.Equal(t, errors.As(expectedErr, &expectedParseErr), errors.As(err, &parseErr), "%s", payload)
+			if expectedParseErr != nil && parseErr != nil {
+				assert.Equal(t, expectedParseErr.Index, parseErr.Index, "%s", payload)
+				assert.Equal(t, expectedParseErr.Field, parseErr.Field, "%s", payload)
+			}
+		}
+	} else {
+		assert.NoError(t, err, "%s", payload)
+	}
+	assert.Equal(t, expected.Time, response.Time, "%s", payload)
+	assert.Equal(t, expected.States, response.States, "%s", payload)
+}
+
+func TestDecodeStatesResponse(t *testing.T) {
+	valid := `["a50c7c",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]`
+	full := `["ae1fa7","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,false,17.95,117.3,-1.3,[1,2,3],952.5,"0753",false,0,6]`
+	cases := []string{
+		// Regular responses
+		`{"time":1624958210,"states":[` + valid + `,` + full + `]}`,
+		string(newStatesPayload(100)),
+		` { "time" : 1624958210 , "states" : [ ` + full + ` ] } `,
+		// Fields in any order, unknown and duplicate fields, case-insensitive keys
+		`{"states":[` + valid + `],"foo":{"bar":[1,"]",{"}":null}]},"time":1624958210}`,
+		`{"TIME":1624958210,"States":[` + valid + `]}`,
+		`{"time":1,"time":2,"states":[` + full + `],"states":[` + valid + `]}`,
+		`{"states":[["a50c7c"]],"states":[` + valid + `]}`,
+		`{"time":1624958210,"states":[]}`,
+		// Empty and null values
+		`{"time":1624958210,"states":[]}`,
+		`{"time":1624958210,"states":null}`,
+		`{"time":null,"states":null}`,
+		`{}`,
+		`null`,
+		// Escaped and non-ASCII strings
+		`{"states":[["a50c7c","\"A\\B\/é😀\"","España",null,1,null,null,null,false,null,null,null,null,null,"\n",false,0]]}`,
+		`{"states":[["a50c7c","Zürich","Österreich",null,1,null,null,null,false,null,null,null,null,null,"\ud800",false,0]]}`,
+		// Numbers in different notations
+		`{"time":1.6e9,"states":[]}`,
+		`{"time":-1,"states":[["a50c7c",null,"United States",1.5,1e3,-0.0,1E-2,2e+2,true,0,0,0,[1.0,2],0,null,true,3]]}`,
+		`{"states":[["a50c7c",null,"United States",null,99999999999999999999,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+		// Invalid values within states
+		`{"states":[` + valid + `,["a50c7c",null,"United States",null,1624891429,null,null,null,"false",null,null,null,null,null,null,false,0]]}`,
+		`{"states":[[1,null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+		`{"states":[["a50c7c",1,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+		`{"states":[["a50c7c",null,"United States","1",1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+		`{"states":[["a50c7c",null,"United States",null,null,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+		`{"states":[["a50c7c",null,"United States",null,1,"1.5",true,{},false,[],null,null,null,null,null,false,0]]}`,
+		`{"states":[["a50c7c",null,"United States",null,1,null,null,null,false,null,null,null,["1"],null,null,false,0]]}`,
+		`{"states":[["a50c7c",null,"United States",null,1,null,null,null,false,null,null,null,{},null,null,false,0]]}`,
+		`{"states":[["a50c7c",null,"United States",null,1,null,null,null,false,null,null,null,null,null,null,false,"0"]]}`,
+		`{"states":[["a50c7c",null,"United States",null,1,null,null,null,false,null,null,null,null,null,null,false,0,null]]}`,
+		`{"states":[["a50c7c",null,"United States"]]}`,
+		`{"states":[null]}`,
+		`{"states":[[]]}`,
+		// Malformed JSON
+		``,
+		`[]`,
+		`"states"`,
+		`{"time":1624958210,"states":{}}`,
+		`{"time":1624958210,"states":[1]}`,
+		`{"time":"now","states":[]}`,
+		`{"time":1624958210,"states":[` + valid,
+		`{"time":1624958210,"states":[` + valid + `]`,
+		`{"time":1624958210,"states":[` + valid + `]}}`,
+		`{"time":1624958210,"states":[` + valid + `],}`,
+		`{"time":01,"states":[]}`,
+		`{"states":[["a50c7c\x"]]}`,
+		"{\"states\":[[\"a50c7c\t\"]]}",
+	}
+	for _, c := range cases {
+		assertDecodeStatesEqual(t, []byte(c))
+	}
+}
+
+func TestDecodeStatesResponseErrorValue(t *testing.T) {
+	payload := `{"states":[["a50c7c",null,"United States",null,1,null,null,null, "yes" ,null,null,null,null,null,null,false,0]]}`
+	_, err := DecodeStatesResponse([]byte(payload), nil)
+	var parseErr *StateParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, 0, parseErr.Index)
+		assert.Equal(t, "on_ground", parseErr.Field)
+		assert.Equal(t, json.RawMessage(`"yes"`), parseErr.Value)
+	}
+	// Error values must not refer to the input, also for null states
+	type testCase struct {
+		payload       string
+		expectedValue json.RawMessage
+	}
+	cases := []testCase{
+		{payload, json.RawMessage(`"yes"`)},
+		{`{"states":[null]}`, json.RawMessage(`null`)},
+	}
+	for _, c := range cases {
+		data := []byte(c.payload)
+		_, err = DecodeStatesResponse(data, nil)
+		for i := range data {
+			data[i] = ' '
+		}
+		if assert.True(t, errors.As(err, &parseErr), c.payload) {
+			assert.Equal(t, c.expectedValue, parseErr.Value, c.payload)
+		}
+	}
+}
+
+func TestDecodeStatesResponseReuse(t *testing.T) {
+	payload := newStatesPayload(10)
+	response, err := DecodeStatesResponse(payload, nil)
+	assert.NoError(t, err)
+	assert.Len(t, response.States, 10)
+	// The memory of the passed states is reused
+	states := response.States
+	response, err = DecodeStatesResponse(payload, states)
+	assert.NoError(t, err)
+	assert.Len(t, response.States, 10)
+	assert.Equal(t, &states[0], &response.States[0])
+	// Decoded values must not refer to the input
+	for i := range payload {
+		payload[i] = ' '
+	}
+	assert.Equal(t, "000001", response.States[1].ICAO24)
+	assert.Equal(t, "TALON71 ", response.States[1].CallSign)
+	assert.Equal(t, time.Unix(1624958210, 0), response.Time)
+}
+
+func BenchmarkDecodeStatesResponseBytes(b *testing.B) {
+	payload := newStatesPayload(10000)
+	var states []State
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	for i := 0; i < b.N; i++ {
+		response, _ := DecodeStatesResponse(payload, states)
+		states = response.States
+	}
+}
@@ -0,0 +1,100 @@
+package opensky
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sentinel errors, which an *APIError matches via errors.Is depending on its status code.
+var (
+	ErrNotFound     = errors.New("opensky: not found")    // 404, e.g. no flights found for the given time period.
+	ErrUnauthorized = errors.New("opensky: unauthorized") // 401 or 403, e.g. invalid credentials.
+	ErrRateLimited  = errors.New("opensky: rate limited") // 429, the API credits are exhausted.
+	ErrServer       = errors.New("opensky: server error") // Any 5xx status code.
+)
+
+// Rate limit information, as returned by OpenSky in the response headers.
+type RateLimit struct {
+	Remaining  *int          // Remaining API credits, from the X-Rate-Limit-Remaining header. Can be nil if the header is not present.
+	RetryAfter time.Duration // Time until credits are available again, from the X-Rate-Limit-Retry-After-Seconds or Retry-After header. Zero if not present.
+}
+
+// Error returned by every Client method, when the API responds with a status code other than 200.
+//
+// Use errors.Is with ErrNotFound, ErrUnauthorized, ErrRateLimited or ErrServer to check for a
+// particular class of errors, or errors.As to access the details.
+type APIError struct {
+	StatusCode int       // HTTP status code of the response.
+	Body       string    // Raw response body.
+	Endpoint   string    // URL path of the request, e.g. /api/states/all.
+	RateLimit  RateLimit // Rate limit headers of the response.
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %d: %v", e.Endpoint, e.StatusCode, e.Body)
+}
+
+// Reports whether the error matches one of the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500 && e.StatusCode < 600
+	}
+	return false
+}
+
+// Parses the rate limit headers of a response.
+// Malformed header values are ignored.
+func parseRateLimit(header http.Header) (rateLimit RateLimit) {
+	if raw := header.Get("X-Rate-Limit-Remaining"); raw != "" {
+		if remaining, err := strconv.Atoi(raw); err == nil {
+			rateLimit.Remaining = &remaining
+		}
+	}
+	raw := header.Get("X-Rate-Limit-Retry-After-Seconds")
+	if raw == "" {
+		raw = header.Get("Retry-After")
+	}
+	if raw != "" {
+		if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil && seconds > 0 {
+			rateLimit.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return
+}
+
+// Error describing an invalid state array in a state vectors response.
+type StateParseError struct {
+	Index       int          // Position of the state array in the response.
+	Field       string       // Name of the invalid field, e.g. icao24. Empty if the state array itself is malformed.
+	Value       interface{}  // Raw value of the invalid field, or the whole state array if Field is empty.
+	Err         error        // Underlying cause. Can be nil.
+	BoundingBox *BoundingBox // Area of the request which returned the state array, if a query was split into several requests, e.g. across the antimeridian or into tiles. Index refers to the response of that request.
+}
+
+func (e *StateParseError) Error() string {
+	position := fmt.Sprintf("position %d", e.Index)
+	if e.BoundingBox != nil {
+		position = fmt.Sprintf("%s of area %+v", position, *e.BoundingBox)
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("invalid state object at %s: %v", position, e.Err)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("invalid %s value at %s: %v", e.Field, position, e.Err)
+	}
+	return fmt.Sprintf("invalid %s value at %s: %v", e.Field, position, e.Value)
+}
+
+func (e *StateParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,158 @@
+package opensky
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newInt(i int) *int {
+	return &i
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	type testCase struct {
+		statusCode int
+		expected   []error
+	}
+	sentinels := []error{ErrNotFound, ErrUnauthorized, ErrRateLimited, ErrServer}
+	cases := []testCase{
+		{http.StatusBadRequest, nil},
+		{http.StatusUnauthorized, []error{ErrUnauthorized}},
+		{http.StatusForbidden, []error{ErrUnauthorized}},
+		{http.StatusNotFound, []error{ErrNotFound}},
+		{http.StatusTooManyRequests, []error{ErrRateLimited}},
+		{http.StatusInternalServerError, []error{ErrServer}},
+		{http.StatusServiceUnavailable, []error{ErrServer}},
+	}
+	for _, c := range cases {
+		var err error = &APIError{StatusCode: c.statusCode}
+		for _, sentinel := range sentinels {
+			expected := false
+			for _, e := range c.expected {
+				expected = expected || e == sentinel
+			}
+			assert.Equal(t, expected, errors.Is(err, sentinel), "%d is %v", c.statusCode, sentinel)
+		}
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	type testCase struct {
+		header   http.Header
+		expected RateLimit
+	}
+	cases := []testCase{
+		{http.Header{}, RateLimit{}},
+		{http.Header{"X-Rate-Limit-Remaining": {"3996"}}, RateLimit{Remaining: newInt(3996)}},
+		{http.Header{"X-Rate-Limit-Remaining": {"0"}, "X-Rate-Limit-Retry-After-Seconds": {"3600"}}, RateLimit{Remaining: newInt(0), RetryAfter: time.Hour}},
+		{http.Header{"Retry-After": {"120"}}, RateLimit{RetryAfter: 2 * time.Minute}},
+		{http.Header{"X-Rate-Limit-Retry-After-Seconds": {"60"}, "Retry-After": {"120"}}, RateLimit{RetryAfter: time.Minute}},
+		{http.Header{"X-Rate-Limit-Remaining": {"many"}, "Retry-After": {"Wed, 21 Oct 2015 07:28:00 GMT"}}, RateLimit{}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, parseRateLimit(c.header))
+	}
+}
+
+func TestDoHTTPAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Rate-Limit-Remaining", "0")
+		w.Header().Set("X-Rate-Limit-Retry-After-Seconds", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("Too many requests"))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL+"/api"))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.False(t, errors.Is(err, ErrNotFound))
+	var apiErr *APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, &APIError{
+			StatusCode: http.StatusTooManyRequests,
+			Body:       "Too many requests",
+			Endpoint:   "/api/states/all",
+			RateLimit:  RateLimit{Remaining: newInt(0), RetryAfter: 42 * time.Second},
+		}, apiErr)
+		assert.Equal(t, "/api/states/all: 429: Too many requests", apiErr.Error())
+	}
+}
+
+func TestEmptyFlightsOnNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	begin := time.Unix(1624860000, 0)
+	end := begin.Add(time.Hour)
+	// Default: 404 is returned as error
+	client := NewClient("", "", WithBaseURL(server.URL))
+	_, err := client.GetFlights(begin, end)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = client.GetFlightsByAircraft("a50c7c", begin, end)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = client.GetArrivalsByAirport("EDDF", begin, end)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	_, err = client.GetTrack("a50c7c", time.Time{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	// Optional: 404 is returned as empty slice
+	client = NewClient("", "", WithBaseURL(server.URL), WithEmptyFlightsOnNotFound())
+	flights, err := client.GetFlights(begin, end)
+	assert.NoError(t, err)
+	assert.Equal(t, []Flight{}, flights)
+	flights, err = client.GetFlightsByAircraft("a50c7c", begin, end)
+	assert.NoError(t, err)
+	assert.Equal(t, []Flight{}, flights)
+	flights, err = client.GetArrivalsByAirport("EDDF", begin, end)
+	assert.NoError(t, err)
+	assert.Equal(t, []Flight{}, flights)
+	flights, err = client.GetDeparturesByAirport("EDDF", begin, end)
+	assert.NoError(t, err)
+	assert.Equal(t, []Flight{}, flights)
+	// Tracks are not affected
+	_, err = client.GetTrack("a50c7c", time.Time{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestLastRateLimit(t *testing.T) {
+	remaining := 4000
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/states/all":
+			remaining -= 4
+			w.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(remaining))
+			_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+		case "/states/own":
+			w.Header().Set("X-Rate-Limit-Remaining", "0")
+			w.Header().Set("X-Rate-Limit-Retry-After-Seconds", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	// Nothing received yet
+	assert.Equal(t, RateLimit{}, client.LastRateLimit())
+	// Successful responses update the rate limit
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, RateLimit{Remaining: newInt(3996)}, client.LastRateLimit())
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, RateLimit{Remaining: newInt(3992)}, client.LastRateLimit())
+	// Responses without headers keep the last values
+	_, err = client.GetFlights(time.Unix(1624860000, 0), time.Unix(1624863600, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, RateLimit{Remaining: newInt(3992)}, client.LastRateLimit())
+	// Error responses update the rate limit
+	_, err = client.GetOwnStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, RateLimit{Remaining: newInt(0), RetryAfter: 30 * time.Second}, client.LastRateLimit())
+}
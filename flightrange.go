@@ -0,0 +1,160 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Maximum time interval accepted by /flights/all.
+	maxFlightsInterval = 2 * time.Hour
+	// Maximum time interval accepted by /flights/aircraft.
+	maxAircraftFlightsInterval = 30 * 24 * time.Hour
+)
+
+// Represents the time interval [Begin, End].
+type TimeWindow struct {
+	Begin time.Time
+	End   time.Time
+}
+
+// Splits the interval [begin, end] into consecutive windows, each spanning at most max.
+// Adjacent windows share their boundaries. Returns nil, if begin is not before end.
+func SplitTimeRange(begin time.Time, end time.Time, max time.Duration) (windows []TimeWindow) {
+	if !begin.Before(end) || max <= 0 {
+		return
+	}
+	for windowBegin := begin; windowBegin.Before(end); windowBegin = windowBegin.Add(max) {
+		windowEnd := windowBegin.Add(max)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		windows = append(windows, TimeWindow{Begin: windowBegin, End: windowEnd})
+	}
+	return
+}
+
+// Retrieves flights for an arbitrarily long time interval, by splitting it into the 2-hour windows
+// accepted by GetFlights.
+//
+// Up to concurrency windows are fetched in parallel; values below 1 fetch them sequentially.
+// Windows without flights are treated as empty. The flights are de-duplicated and ordered by
+// their FirstSeen time. If any window fails, the first error is returned.
+func (c *Client) GetFlightsRange(begin time.Time, end time.Time, concurrency int) (flights []Flight, err error) {
+	return c.GetFlightsRangeContext(context.Background(), begin, end, concurrency)
+}
+
+// Same as GetFlightsRange, but the passed context controls cancellation and deadline of the requests.
+func (c *Client) GetFlightsRangeContext(ctx context.Context, begin time.Time, end time.Time, concurrency int) (flights []Flight, err error) {
+	var v validator
+	v.interval(begin, end, 0, true)
+	if err = v.err(); err != nil {
+		return
+	}
+	return c.getFlightsRange(ctx, begin, end, maxFlightsInterval, concurrency, func(ctx context.Context, window TimeWindow) ([]Flight, error) {
+		return c.GetFlightsContext(ctx, window.Begin, window.End)
+	})
+}
+
+// Retrieves flights of a particular aircraft for an arbitrarily long time interval, by splitting it
+// into the 30-day windows accepted by GetFlightsByAircraft.
+//
+// Up to concurrency windows are fetched in parallel; values below 1 fetch them sequentially.
+// Windows without flights are treated as empty. The flights are de-duplicated and ordered by
+// their FirstSeen time. If any window fails, the first error is returned.
+func (c *Client) GetFlightsByAircraftRange(icao24 string, begin time.Time, end time.Time, concurrency int) (flights []Flight, err error) {
+	return c.GetFlightsByAircraftRangeContext(context.Background(), icao24, begin, end, concurrency)
+}
+
+// Same as GetFlightsByAircraftRange, but the passed context controls cancellation and deadline of the requests.
+func (c *Client) GetFlightsByAircraftRangeContext(ctx context.Context, icao24 string, begin time.Time, end time.Time, concurrency int) (flights []Flight, err error) {
+	var v validator
+	icao24 = v.icao24(icao24)
+	v.interval(begin, end, 0, true)
+	if err = v.err(); err != nil {
+		return
+	}
+	return c.getFlightsRange(ctx, begin, end, maxAircraftFlightsInterval, concurrency, func(ctx context.Context, window TimeWindow) ([]Flight, error) {
+		return c.GetFlightsByAircraftContext(ctx, icao24, window.Begin, window.End)
+	})
+}
+
+// Fetches the windows of [begin, end] with a bounded number of workers and merges the results.
+func (c *Client) getFlightsRange(ctx context.Context, begin time.Time, end time.Time, max time.Duration, concurrency int, fetch func(ctx context.Context, window TimeWindow) ([]Flight, error)) (flights []Flight, err error) {
+	windows := SplitTimeRange(begin, end, max)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(windows) {
+		concurrency = len(windows)
+	}
+	// Remaining windows are skipped after the first failure
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]Flight, len(windows))
+	indexes := make(chan int)
+	var once sync.Once
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				windowFlights, windowErr := fetch(ctx, windows[i])
+				if windowErr != nil && !errors.Is(windowErr, ErrNotFound) {
+					once.Do(func() {
+						err = windowErr
+						cancel()
+					})
+					continue
+				}
+				results[i] = windowFlights
+			}
+		}()
+	}
+	for i := range windows {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return mergeFlights(results), nil
+}
+
+// Identifies a flight, for removing flights which are reported in several windows.
+type flightKey struct {
+	icao24    string
+	firstSeen int64
+	lastSeen  int64
+	callSign  string
+}
+
+// Concatenates the passed flights, removes duplicates and sorts them by FirstSeen.
+func mergeFlights(results [][]Flight) (flights []Flight) {
+	seen := map[flightKey]bool{}
+	for _, result := range results {
+		for _, flight := range result {
+			key := flightKey{flight.ICAO24, flight.FirstSeen.Unix(), flight.LastSeen.Unix(), flight.CallSign}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			flights = append(flights, flight)
+		}
+	}
+	sort.SliceStable(flights, func(i, j int) bool {
+		return flights[i].FirstSeen.Before(flights[j].FirstSeen.Time)
+	})
+	return
+}
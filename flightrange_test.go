@@ -0,0 +1,128 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitTimeRange(t *testing.T) {
+	type testCase struct {
+		begin           int64
+		end             int64
+		max             time.Duration
+		expectedWindows [][2]int64
+	}
+	cases := []testCase{
+		{0, 7200, 2 * time.Hour, [][2]int64{{0, 7200}}},
+		{0, 3600, 2 * time.Hour, [][2]int64{{0, 3600}}},
+		{0, 7201, 2 * time.Hour, [][2]int64{{0, 7200}, {7200, 7201}}},
+		{0, 18000, 2 * time.Hour, [][2]int64{{0, 7200}, {7200, 14400}, {14400, 18000}}},
+		{100, 100, 2 * time.Hour, nil},
+		{200, 100, 2 * time.Hour, nil},
+		{0, 100, 0, nil},
+	}
+	for _, c := range cases {
+		var expected []TimeWindow
+		for _, w := range c.expectedWindows {
+			expected = append(expected, TimeWindow{Begin: time.Unix(w[0], 0), End: time.Unix(w[1], 0)})
+		}
+		assert.Equal(t, expected, SplitTimeRange(time.Unix(c.begin, 0), time.Unix(c.end, 0), c.max), "%v-%v", c.begin, c.end)
+	}
+}
+
+func TestGetFlightsRange(t *testing.T) {
+	begin := time.Unix(1624860000, 0)
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Query().Get("begin")+"-"+r.URL.Query().Get("end"))
+		mu.Unlock()
+		windowBegin, _ := strconv.ParseInt(r.URL.Query().Get("begin"), 10, 64)
+		switch windowBegin - begin.Unix() {
+		case 0:
+			// The flight spanning both windows is reported twice
+			fmt.Fprintf(w, `[{"icao24":"3c6444","firstSeen":%d,"lastSeen":%d,"callsign":"DLH900  "},{"icao24":"a50c7c","firstSeen":%d,"lastSeen":%d}]`,
+				windowBegin+7000, windowBegin+8000, windowBegin+100, windowBegin+200)
+		case 7200:
+			fmt.Fprintf(w, `[{"icao24":"3c6444","firstSeen":%d,"lastSeen":%d,"callsign":"DLH900  "},{"icao24":"a50c7c","firstSeen":%d,"lastSeen":%d}]`,
+				windowBegin-200, windowBegin+800, windowBegin+100, windowBegin+200)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	for _, concurrency := range []int{0, 1, 3, 10} {
+		requested = nil
+		flights, err := client.GetFlightsRange(begin, begin.Add(5*time.Hour), concurrency)
+		assert.NoError(t, err)
+		expected := []Flight{
+			{ICAO24: "a50c7c", FirstSeen: newUnixTime(1624860100), LastSeen: newUnixTime(1624860200)},
+			{ICAO24: "3c6444", FirstSeen: newUnixTime(1624867000), LastSeen: newUnixTime(1624868000), CallSign: "DLH900  "},
+			{ICAO24: "a50c7c", FirstSeen: newUnixTime(1624867300), LastSeen: newUnixTime(1624867400)},
+		}
+		assert.Equal(t, expected, flights)
+		assert.ElementsMatch(t, []string{"1624860000-1624867200", "1624867200-1624874400", "1624874400-1624878000"}, requested)
+	}
+}
+
+func TestGetFlightsByAircraftRange(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	begin := time.Unix(1624860000, 0)
+	flights, err := client.GetFlightsByAircraftRange("3c6444", begin, begin.Add(45*24*time.Hour), 1)
+	assert.NoError(t, err)
+	assert.Empty(t, flights)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "/flights/aircraft", requests[0].URL.Path)
+		assert.Equal(t, "3c6444", requests[0].URL.Query().Get("icao24"))
+		assert.Equal(t, "1624860000", requests[0].URL.Query().Get("begin"))
+		assert.Equal(t, "1627452000", requests[0].URL.Query().Get("end"))
+		assert.Equal(t, "1627452000", requests[1].URL.Query().Get("begin"))
+	}
+}
+
+func TestGetFlightsRangeErrors(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	begin := time.Unix(1624860000, 0)
+	// The first failing window aborts the remaining ones
+	flights, err := client.GetFlightsRange(begin, begin.Add(24*time.Hour), 1)
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Nil(t, flights)
+	assert.Equal(t, 1, requests)
+	// Invalid intervals
+	_, err = client.GetFlightsRange(begin, begin, 1)
+	assert.Error(t, err)
+	_, err = client.GetFlightsRange(time.Time{}, begin, 1)
+	assert.Error(t, err)
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetFlightsRangeContext(ctx, begin, begin.Add(24*time.Hour), 4)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, requests)
+}
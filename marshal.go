@@ -0,0 +1,90 @@
+package opensky
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Wire format of a state vectors response, as returned by /states/all and /states/own.
+type statesResponseJSON struct {
+	Time   *int64          `json:"time"`
+	States [][]interface{} `json:"states"`
+}
+
+// Encodes a state vectors response in the JSON format returned by /states/all and /states/own,
+// where each state is a positional array.
+//
+// Unset values, i.e. nil pointers, empty strings and empty sensor lists, are encoded as null.
+// The category is appended as 18th element, if any state has a category, like in responses to
+// extended queries. A zero response time is encoded as null. Parse errors are not encoded.
+func MarshalStatesResponse(response GetStatesResponse) ([]byte, error) {
+	var raw statesResponseJSON
+	if !response.Time.IsZero() {
+		t := response.Time.Unix()
+		raw.Time = &t
+	}
+	extended := false
+	for _, state := range response.States {
+		if state.Category != CategoryNoInformation {
+			extended = true
+			break
+		}
+	}
+	if response.States != nil {
+		raw.States = make([][]interface{}, 0, len(response.States))
+	}
+	for _, state := range response.States {
+		raw.States = append(raw.States, marshalState(state, extended))
+	}
+	return json.Marshal(raw)
+}
+
+// Decodes a state vectors response in the JSON format returned by /states/all and /states/own.
+// It is the inverse of MarshalStatesResponse.
+//
+// Unlike DecodeStatesResponse and GetStates, a null or absent response time is decoded as the
+// zero time.Time instead of the unix epoch, so that the zero time survives a round trip.
+func UnmarshalStatesResponse(data []byte) (response GetStatesResponse, err error) {
+	return decodeStatesResponse(data, nil, time.Time{})
+}
+
+// Converts a state into its positional array representation.
+func marshalState(state State, extended bool) []interface{} {
+	s := []interface{}{
+		state.ICAO24,
+		nullableString(state.CallSign),
+		state.OriginCountry,
+		nil,
+		state.LastContact.Unix(),
+		state.Longitude,
+		state.Latitude,
+		state.BarometricAltitude,
+		state.OnGround,
+		state.Velocity,
+		state.Heading,
+		state.VerticalRate,
+		nil,
+		state.GeoAltitude,
+		nullableString(state.Squawk),
+		state.Spi,
+		int(state.PositionSource),
+	}
+	if state.TimePosition != nil {
+		s[3] = state.TimePosition.Unix()
+	}
+	if len(state.Sensors) > 0 {
+		s[12] = state.Sensors
+	}
+	if extended {
+		s = append(s, int(state.Category))
+	}
+	return s
+}
+
+// Returns nil for an empty string, and the string otherwise.
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
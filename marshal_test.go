@@ -0,0 +1,62 @@
+package opensky
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalStatesResponse(t *testing.T) {
+	type testCase struct {
+		payload string
+	}
+	cases := []testCase{
+		{`{"time":1624958210,"states":[["a50c7c",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`},
+		{`{"time":1624958210,"states":[["ae1fa7","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,false,17.95,117.3,-1.3,[1,2,3],952.5,"0753",false,0]]}`},
+		// Extended states
+		{`{"time":1624958210,"states":[["ae1fa7","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,true,0,0,0,null,0,null,true,2,6],["a50c7c",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0,0]]}`},
+		// Empty responses
+		{`{"time":1624958210,"states":null}`},
+		{`{"time":null,"states":null}`},
+	}
+	for _, c := range cases {
+		response, err := UnmarshalStatesResponse([]byte(c.payload))
+		assert.NoError(t, err, c.payload)
+		data, err := MarshalStatesResponse(response)
+		assert.NoError(t, err, c.payload)
+		assert.JSONEq(t, c.payload, string(data))
+	}
+}
+
+func TestMarshalStatesResponseRoundTrip(t *testing.T) {
+	response, err := UnmarshalStatesResponse(newStatesPayload(100))
+	assert.NoError(t, err)
+	states := append(response.States, State{
+		ICAO24:        "3c6444",
+		OriginCountry: "Germany",
+		TimePosition:  newUnixTimeP(1624891429),
+		LastContact:   newUnixTime(1624891430),
+		Latitude:      newFloat(50.0333),
+		Longitude:     newFloat(8.5706),
+		Sensors:       []int{-1, 0, 1},
+		Squawk:        "1000",
+		Category:      CategoryHeavy,
+	})
+	response.States = states
+	data, err := MarshalStatesResponse(response)
+	assert.NoError(t, err)
+	decoded, err := UnmarshalStatesResponse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, response, decoded)
+
+	// Zero response
+	data, err = MarshalStatesResponse(GetStatesResponse{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"time":null,"states":null}`, string(data))
+	decoded, err = UnmarshalStatesResponse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, GetStatesResponse{}, decoded)
+	decoded, err = UnmarshalStatesResponse([]byte(`{"states":null}`))
+	assert.NoError(t, err)
+	assert.Equal(t, GetStatesResponse{}, decoded)
+}
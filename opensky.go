@@ -1,11 +1,14 @@
 package opensky
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,33 +18,97 @@ type PositionSource int
 const (
 	baseOpenSkyURL = "https://opensky-network.org/api"
 
+	// Maximum time interval accepted by the airport arrival and departure endpoints.
+	maxAirportFlightsInterval = 7 * 24 * time.Hour
+
 	ADSB    PositionSource = 0
 	ASTERIX PositionSource = 1
 	MLAT    PositionSource = 2
 	FLARM   PositionSource = 3
 )
 
+// Category of an aircraft, as reported by its ADS-B emitter.
+// The category is only available for extended state vectors.
+type AircraftCategory int
+
+const (
+	CategoryNoInformation     AircraftCategory = 0  // No information at all.
+	CategoryNoADSBInformation AircraftCategory = 1  // No ADS-B emitter category information.
+	CategoryLight             AircraftCategory = 2  // Light (< 15500 lbs).
+	CategorySmall             AircraftCategory = 3  // Small (15500 to 75000 lbs).
+	CategoryLarge             AircraftCategory = 4  // Large (75000 to 300000 lbs).
+	CategoryHighVortexLarge   AircraftCategory = 5  // High vortex large (aircraft such as B-757).
+	CategoryHeavy             AircraftCategory = 6  // Heavy (> 300000 lbs).
+	CategoryHighPerformance   AircraftCategory = 7  // High performance (> 5g acceleration and 400 kts).
+	CategoryRotorcraft        AircraftCategory = 8  // Rotorcraft.
+	CategoryGlider            AircraftCategory = 9  // Glider / sailplane.
+	CategoryLighterThanAir    AircraftCategory = 10 // Lighter-than-air.
+	CategoryParachutist       AircraftCategory = 11 // Parachutist / skydiver.
+	CategoryUltralight        AircraftCategory = 12 // Ultralight / hang-glider / paraglider.
+	CategoryReserved          AircraftCategory = 13 // Reserved.
+	CategoryUAV               AircraftCategory = 14 // Unmanned aerial vehicle.
+	CategorySpace             AircraftCategory = 15 // Space / trans-atmospheric vehicle.
+	CategoryEmergencyVehicle  AircraftCategory = 16 // Surface vehicle – emergency vehicle.
+	CategoryServiceVehicle    AircraftCategory = 17 // Surface vehicle – service vehicle.
+	CategoryPointObstacle     AircraftCategory = 18 // Point obstacle (includes tethered balloons).
+	CategoryClusterObstacle   AircraftCategory = 19 // Cluster obstacle.
+	CategoryLineObstacle      AircraftCategory = 20 // Line obstacle.
+)
+
+var aircraftCategoryNames = []string{
+	"No information",
+	"No ADS-B emitter category information",
+	"Light",
+	"Small",
+	"Large",
+	"High vortex large",
+	"Heavy",
+	"High performance",
+	"Rotorcraft",
+	"Glider",
+	"Lighter-than-air",
+	"Parachutist",
+	"Ultralight",
+	"Reserved",
+	"Unmanned aerial vehicle",
+	"Space vehicle",
+	"Emergency vehicle",
+	"Service vehicle",
+	"Point obstacle",
+	"Cluster obstacle",
+	"Line obstacle",
+}
+
+// Returns a human-readable description of the aircraft category.
+func (c AircraftCategory) String() string {
+	if c < 0 || int(c) >= len(aircraftCategoryNames) {
+		return fmt.Sprintf("AircraftCategory(%d)", int(c))
+	}
+	return aircraftCategoryNames[c]
+}
+
 // Represents the state of a vehicle at a particular time.
 //
 // All pointer fields are nullable, therefore checks are required, before accessing those fields.
 type State struct {
-	ICAO24             string         `json:"icao24"`                  // ICAO24 address of the transmitter in hex string representation.
-	CallSign           string         `json:"callsign,omitempty"`      // CallSign of the vehicle. Can be nil if no callsign has been received.
-	OriginCountry      string         `json:"origin_country"`          // Inferred through the ICAO24 address.
-	TimePosition       *UnixTime      `json:"time_position,omitempty"` // UnixTime of last position report. Can be nil if there was no position report received by OpenSky within 15s before.
-	LastContact        UnixTime       `json:"last_contact"`            // UnixTime of last received message from this transponder.
-	Longitude          *float64       `json:"longitude,omitempty"`     // In ellipsoidal coordinates (WGS-84) and degrees. Can be nil.
-	Latitude           *float64       `json:"latitude,omitempty"`      // In ellipsoidal coordinates (WGS-84) and degrees. Can be nil.
-	GeoAltitude        *float64       `json:"geo_altitude,omitempty"`  // Geometric altitude in meters. Can be nil.
-	OnGround           bool           `json:"on_ground"`               // True if aircraft is on ground (sends ADS-B surface position reports).
-	Velocity           *float64       `json:"velocity,omitempty"`      // Velocity over ground in m/s. Can be nil if information not present.
-	Heading            *float64       `json:"heading,omitempty"`       // Heading in decimal degrees (0 is north). Can be nil if information not present.
-	VerticalRate       *float64       `json:"vertical_rate,omitempty"` // In m/s, incline is positive, decline negative. Can be nil if information not present.
-	Sensors            []int          `json:"sensors,omitempty"`       // Serial numbers of sensors which received messages from the vehicle within the validity period of this state vector. Can be nil if no filtering for sensor has been requested.
-	BarometricAltitude *float64       `json:"baro_altitude,omitempty"` // Barometric altitude in meters. Can be nil.
-	Squawk             string         `json:"squawk,omitempty"`        // Transponder code aka Squawk. Can be empty.
-	Spi                bool           `json:"spi"`                     // Special purpose indicator.
-	PositionSource     PositionSource `json:"position_source"`         // Origin of this state’s position.
+	ICAO24             string           `json:"icao24"`                  // ICAO24 address of the transmitter in hex string representation.
+	CallSign           string           `json:"callsign,omitempty"`      // CallSign of the vehicle. Can be nil if no callsign has been received.
+	OriginCountry      string           `json:"origin_country"`          // Inferred through the ICAO24 address.
+	TimePosition       *UnixTime        `json:"time_position,omitempty"` // UnixTime of last position report. Can be nil if there was no position report received by OpenSky within 15s before.
+	LastContact        UnixTime         `json:"last_contact"`            // UnixTime of last received message from this transponder.
+	Longitude          *float64         `json:"longitude,omitempty"`     // In ellipsoidal coordinates (WGS-84) and degrees. Can be nil.
+	Latitude           *float64         `json:"latitude,omitempty"`      // In ellipsoidal coordinates (WGS-84) and degrees. Can be nil.
+	GeoAltitude        *float64         `json:"geo_altitude,omitempty"`  // Geometric altitude in meters. Can be nil.
+	OnGround           bool             `json:"on_ground"`               // True if aircraft is on ground (sends ADS-B surface position reports).
+	Velocity           *float64         `json:"velocity,omitempty"`      // Velocity over ground in m/s. Can be nil if information not present.
+	Heading            *float64         `json:"heading,omitempty"`       // Heading in decimal degrees (0 is north). Can be nil if information not present.
+	VerticalRate       *float64         `json:"vertical_rate,omitempty"` // In m/s, incline is positive, decline negative. Can be nil if information not present.
+	Sensors            []int            `json:"sensors,omitempty"`       // Serial numbers of sensors which received messages from the vehicle within the validity period of this state vector. Can be nil if no filtering for sensor has been requested.
+	BarometricAltitude *float64         `json:"baro_altitude,omitempty"` // Barometric altitude in meters. Can be nil.
+	Squawk             string           `json:"squawk,omitempty"`        // Transponder code aka Squawk. Can be empty.
+	Spi                bool             `json:"spi"`                     // Special purpose indicator.
+	PositionSource     PositionSource   `json:"position_source"`         // Origin of this state’s position.
+	Category           AircraftCategory `json:"category,omitempty"`      // Aircraft category. Only set for extended state vectors, CategoryNoInformation otherwise.
 }
 
 // Represents a single flight of an aircraft.
@@ -61,19 +128,61 @@ type Flight struct {
 }
 
 // Bounding box of WGS84 coordinates.
+//
+// If LonMin is greater than LonMax, the box crosses the antimeridian, i.e. it spans from LonMin
+// eastwards across 180° to LonMax. For example, LonMin 170 and LonMax -170 describe a box
+// 20 degrees wide, centered on the antimeridian.
 type BoundingBox struct {
 	LatMin float64 `json:"lamin"` // Lower bound for the latitude in decimal degrees.
-	LonMin float64 `json:"lomin"` // Lower bound for the longitude in decimal degrees.
+	LonMin float64 `json:"lomin"` // Western bound for the longitude in decimal degrees.
 	LatMax float64 `json:"lamax"` // Upper bound for the latitude in decimal degrees.
-	LonMax float64 `json:"lomax"` // Upper bound for the longitude in decimal degrees.
+	LonMax float64 `json:"lomax"` // Eastern bound for the longitude in decimal degrees.
+}
+
+// Reports whether the box crosses the antimeridian, i.e. LonMin is greater than LonMax.
+func (b BoundingBox) CrossesAntimeridian() bool {
+	return b.LonMin > b.LonMax
+}
+
+// Reports whether the passed coordinates are inside the box, including its bounds.
+func (b BoundingBox) Contains(lat float64, lon float64) bool {
+	if lat < b.LatMin || lat > b.LatMax {
+		return false
+	}
+	if b.CrossesAntimeridian() {
+		return lon >= b.LonMin || lon <= b.LonMax
+	}
+	return lon >= b.LonMin && lon <= b.LonMax
+}
+
+// Splits a box crossing the antimeridian into the parts west and east of it.
+// Boxes which do not cross the antimeridian are returned as they are.
+func (b BoundingBox) Split() []BoundingBox {
+	if !b.CrossesAntimeridian() {
+		return []BoundingBox{b}
+	}
+	return []BoundingBox{
+		{LatMin: b.LatMin, LonMin: b.LonMin, LatMax: b.LatMax, LonMax: 180},
+		{LatMin: b.LatMin, LonMin: -180, LatMax: b.LatMax, LonMax: b.LonMax},
+	}
 }
 
 // An OpenSky API client.
 // To instantiate a new client, use the NewClient function.
 type Client struct {
-	username   string
-	password   string
-	httpClient http.Client
+	auth       Authenticator
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+	timeout    *time.Duration // Timeout set by WithTimeout, applied after all options.
+
+	emptyFlightsOnNotFound bool
+	retryPolicy            *RetryPolicy
+	creditBudget           *CreditBudget
+	lenientParsing         bool
+
+	rateLimitMu   sync.Mutex
+	lastRateLimit RateLimit
 }
 
 // Unstructured raw response for state queries.
@@ -82,32 +191,58 @@ type unstructuredStateResponse struct {
 	States [][]interface{} `json:"states"`
 }
 
+// Decodes a raw state response, keeping all numbers inside the state arrays as json.Number.
+// This way integer values, such as timestamps and sensor serials, are not subject to float rounding.
+func (r *unstructuredStateResponse) UnmarshalJSON(data []byte) error {
+	type rawResponse unstructuredStateResponse
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode((*rawResponse)(r))
+}
+
 // The response for state vectors.
 type GetStatesResponse struct {
-	Time   time.Time `json:"time"`
-	States []State   `json:"states"`
+	Time   time.Time          `json:"time"`
+	States []State            `json:"states"`
+	Errors []*StateParseError `json:"-"` // State arrays skipped due to parse errors. Only set if the client was created with WithLenientParsing.
 }
 
 // Creates a new OpenSky client.
-// Username and password fields are optional.
-func NewClient(username string, password string) *Client {
-	return &Client{
-		username: username,
-		password: password,
-		httpClient: http.Client{
+// Username and password fields are optional. If both are set, HTTP basic authentication is used.
+//
+// Additional options may be passed to customize the client, e.g. WithBaseURL or WithHTTPClient.
+// Use WithAuthenticator for other authentication methods, such as OAuth2 client credentials.
+func NewClient(username string, password string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: baseOpenSkyURL,
+		httpClient: &http.Client{
 			Timeout: time.Minute * 5,
 		},
 	}
+	if username != "" && password != "" {
+		c.auth = NewBasicAuthenticator(username, password)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.timeout != nil {
+		// Copy the HTTP client, so that one passed with WithHTTPClient is not modified
+		httpClient := *c.httpClient
+		httpClient.Timeout = *c.timeout
+		c.httpClient = &httpClient
+	}
+	return c
 }
 
-// Creates a new HTTP request, with the basic authentication header already set.
-func (c *Client) newRequest(method string, apiURL string) (request *http.Request, err error) {
-	request, err = http.NewRequest(method, apiURL, nil)
+// Creates a new HTTP request bound to the passed context, with the user agent header already set.
+// Credentials are added separately for every attempt, see doOnce.
+func (c *Client) newRequest(ctx context.Context, method string, apiURL string) (request *http.Request, err error) {
+	request, err = http.NewRequestWithContext(ctx, method, apiURL, nil)
 	if err != nil {
 		return
 	}
-	if request != nil && c.username != "" && c.password != "" {
-		request.SetBasicAuth(c.username, c.password)
+	if c.userAgent != "" {
+		request.Header.Set("User-Agent", c.userAgent)
 	}
 	return
 }
@@ -116,10 +251,10 @@ func (c *Client) newRequest(method string, apiURL string) (request *http.Request
 // JSON response inside the passed responseObject.
 //
 // If the operation fails for any reason, an error is returned.
-// If the HTTP request returns any status code other than 200, an error is returned.
+// If the HTTP request returns any status code other than 200, an *APIError is returned.
 func (c *Client) doHTTP(request *http.Request, responseObject interface{}) (err error) {
 	var resp *http.Response
-	resp, err = c.httpClient.Do(request)
+	resp, err = c.do(request)
 	if err != nil {
 		return
 	}
@@ -130,10 +265,6 @@ func (c *Client) doHTTP(request *http.Request, responseObject interface{}) (err
 	if err != nil {
 		return
 	}
-	if resp.StatusCode != http.StatusOK {
-		err = fmt.Errorf("%d: %v", resp.StatusCode, string(body))
-		return
-	}
 	// Parse JSON
 	err = json.Unmarshal(body, responseObject)
 	if err != nil {
@@ -142,6 +273,74 @@ func (c *Client) doHTTP(request *http.Request, responseObject interface{}) (err
 	return nil
 }
 
+// Performs an HTTP request, retrying it according to the retry policy of the client.
+//
+// On success, the response is returned and the caller is responsible for closing its body.
+// If the HTTP request returns any status code other than 200, an *APIError is returned.
+func (c *Client) do(request *http.Request) (resp *http.Response, err error) {
+	if c.retryPolicy == nil || !isIdempotent(request.Method) {
+		return c.doOnce(request)
+	}
+	return c.retryPolicy.do(request, c.doOnce)
+}
+
+// Performs a single attempt of an HTTP request.
+// Any response with a status code other than 200 is consumed and returned as *APIError.
+//
+// The request is authenticated on a copy for every attempt, so that retries pick up refreshed
+// credentials, e.g. a new bearer token after the previous one expired during a backoff.
+func (c *Client) doOnce(request *http.Request) (resp *http.Response, err error) {
+	if c.auth != nil {
+		request = request.Clone(request.Context())
+		if err = c.auth.Authenticate(request); err != nil {
+			return
+		}
+	}
+	resp, err = c.httpClient.Do(request)
+	if err != nil {
+		return
+	}
+	c.updateRateLimit(resp.Header)
+	if resp.StatusCode == http.StatusOK {
+		return
+	}
+	defer resp.Body.Close()
+	var body []byte
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Endpoint:   request.URL.Path,
+		RateLimit:  parseRateLimit(resp.Header),
+	}
+	return nil, err
+}
+
+// Returns the rate limit information of the most recent response, which contained rate limit headers.
+// The Remaining field is nil, if no such response was received yet.
+//
+// Since OpenSky only sends the headers for some endpoints (e.g. state vectors), responses without
+// rate limit headers do not reset the last observed values.
+func (c *Client) LastRateLimit() RateLimit {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	return c.lastRateLimit
+}
+
+// Stores the rate limit headers of a response, if there are any.
+func (c *Client) updateRateLimit(header http.Header) {
+	rateLimit := parseRateLimit(header)
+	if rateLimit.Remaining == nil && rateLimit.RetryAfter == 0 {
+		return
+	}
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	c.lastRateLimit = rateLimit
+}
+
 // Retrieves any state vectors from OpenSky, at the specified timestamp and
 // according to the additional optional filters.
 //
@@ -152,34 +351,41 @@ func (c *Client) doHTTP(request *http.Request, responseObject interface{}) (err
 //
 // If a bounding box is passed, then only the specified area will be queried.
 func (c *Client) GetStates(time time.Time, icao24 []string, bbox *BoundingBox) (response GetStatesResponse, err error) {
-	request, err := c.newRequest("GET", fmt.Sprintf("%s/states/all", baseOpenSkyURL))
-	if err != nil {
-		return
-	}
-	q := request.URL.Query()
-	//request := c.baseRequest.Clone().
-	//	Get(fmt.Sprintf("%s/states/all", baseOpenSkyURL))
-	// Add optional parameters
-	if !time.IsZero() {
-		q.Set("time", fmt.Sprintf("%v", time.Unix()))
-	}
-	if icao24 != nil && len(icao24) > 0 {
-		q.Set("icao24", strings.Join(icao24, ","))
-	}
-	if bbox != nil {
-		q.Set("lamin", fmt.Sprintf("%v", bbox.LatMin))
-		q.Set("lomin", fmt.Sprintf("%v", bbox.LonMin))
-		q.Set("lamax", fmt.Sprintf("%v", bbox.LatMax))
-		q.Set("lomax", fmt.Sprintf("%v", bbox.LonMax))
+	return c.GetStatesContext(context.Background(), time, icao24, bbox)
+}
+
+// Same as GetStates, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetStatesContext(ctx context.Context, time time.Time, icao24 []string, bbox *BoundingBox) (response GetStatesResponse, err error) {
+	return c.QueryStates(ctx, StatesQuery{Time: time, ICAO24: icao24, BoundingBox: bbox})
+}
+
+// Retrieves any extended state vectors from OpenSky, at the specified timestamp and
+// according to the additional optional filters.
+//
+// Extended state vectors behave exactly like the ones returned by GetStates, but additionally
+// contain the aircraft category.
+func (c *Client) GetExtendedStates(time time.Time, icao24 []string, bbox *BoundingBox) (response GetStatesResponse, err error) {
+	return c.GetExtendedStatesContext(context.Background(), time, icao24, bbox)
+}
+
+// Same as GetExtendedStates, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetExtendedStatesContext(ctx context.Context, time time.Time, icao24 []string, bbox *BoundingBox) (response GetStatesResponse, err error) {
+	return c.QueryStates(ctx, StatesQuery{Time: time, ICAO24: icao24, BoundingBox: bbox, Extended: true})
+}
+
+// Charges the passed cost to the credit budget of the client, if there is any.
+func (c *Client) reserveCredits(cost int) error {
+	if c.creditBudget == nil {
+		return nil
 	}
-	request.URL.RawQuery = q.Encode()
-	// Fetch response
-	var rawResponse unstructuredStateResponse
-	err = c.doHTTP(request, &rawResponse)
-	if err != nil {
-		return
+	return c.creditBudget.reserve(cost)
+}
+
+// Returns the passed cost to the credit budget of the client, if there is any.
+func (c *Client) refundCredits(cost int) {
+	if c.creditBudget != nil {
+		c.creditBudget.refund(cost)
 	}
-	return parseStatesResponse(rawResponse)
 }
 
 // Retrieves state vectors from OpenSky for your own sensors (without rate limitations),
@@ -194,44 +400,31 @@ func (c *Client) GetStates(time time.Time, icao24 []string, bbox *BoundingBox) (
 // parameter. In this case, the API returns states of aircraft that are visible to at
 // least one of the given receivers.
 func (c *Client) GetOwnStates(time time.Time, icao24 []string, serials []int) (response GetStatesResponse, err error) {
-	request, err := c.newRequest("GET", fmt.Sprintf("%s/states/own", baseOpenSkyURL))
-	if err != nil {
-		return
-	}
-	q := request.URL.Query()
-	// Add optional parameters
-	if !time.IsZero() {
-		q.Set("time", fmt.Sprintf("%v", time.Unix()))
-	}
-	if icao24 != nil && len(icao24) > 0 {
-		q.Set("icao24", strings.Join(icao24, ","))
-	}
-	if serials != nil && len(serials) > 0 {
-		serialQuery := ""
-		for i, s := range serials {
-			if i > 0 {
-				serialQuery += ","
-			}
-			serialQuery += fmt.Sprintf("%v", s)
-		}
-		q.Set("serials", serialQuery)
-	}
-	request.URL.RawQuery = q.Encode()
-	// Fetch response
-	var rawResponse unstructuredStateResponse
-	err = c.doHTTP(request, &rawResponse)
-	if err != nil {
-		return
-	}
-	return parseStatesResponse(rawResponse)
+	return c.GetOwnStatesContext(context.Background(), time, icao24, serials)
+}
+
+// Same as GetOwnStates, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetOwnStatesContext(ctx context.Context, time time.Time, icao24 []string, serials []int) (response GetStatesResponse, err error) {
+	return c.QueryStates(ctx, StatesQuery{Time: time, ICAO24: icao24, Serials: serials, Own: true})
 }
 
 // Retrieves all flight information within a certain time interval.
 // Flights departed and arrived within the [begin, end] boundaries will be returned.
 //
-// If no flights were found for the given time period, a 404 error will be returned instead.
+// If no flights were found for the given time period, an error matching ErrNotFound will be
+// returned instead, unless the client was created with WithEmptyFlightsOnNotFound.
 func (c *Client) GetFlights(begin time.Time, end time.Time) (flights []Flight, err error) {
-	request, err := c.newRequest("GET", fmt.Sprintf("%s/flights/all", baseOpenSkyURL))
+	return c.GetFlightsContext(context.Background(), begin, end)
+}
+
+// Same as GetFlights, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetFlightsContext(ctx context.Context, begin time.Time, end time.Time) (flights []Flight, err error) {
+	var v validator
+	v.interval(begin, end, 0, false)
+	if err = v.err(); err != nil {
+		return
+	}
+	request, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/flights/all", c.baseURL))
 	if err != nil {
 		return
 	}
@@ -246,16 +439,28 @@ func (c *Client) GetFlights(begin time.Time, end time.Time) (flights []Flight, e
 	request.URL.RawQuery = q.Encode()
 	// Fetch response
 	err = c.doHTTP(request, &flights)
-	return
+	return c.handleFlightsNotFound(flights, err)
 }
 
 // Retrieves flight information for a particular aircraft, identified by the icao24 address parameter,
 // within a certain time interval.
 // Flights departed and arrived within the [begin, end] boundaries will be returned.
 //
-// If no flights were found for the given time period, a 404 error will be returned instead.
+// If no flights were found for the given time period, an error matching ErrNotFound will be
+// returned instead, unless the client was created with WithEmptyFlightsOnNotFound.
 func (c *Client) GetFlightsByAircraft(icao24 string, begin time.Time, end time.Time) (flights []Flight, err error) {
-	request, err := c.newRequest("GET", fmt.Sprintf("%s/flights/aircraft", baseOpenSkyURL))
+	return c.GetFlightsByAircraftContext(context.Background(), icao24, begin, end)
+}
+
+// Same as GetFlightsByAircraft, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetFlightsByAircraftContext(ctx context.Context, icao24 string, begin time.Time, end time.Time) (flights []Flight, err error) {
+	var v validator
+	icao24 = v.icao24(icao24)
+	v.interval(begin, end, 0, false)
+	if err = v.err(); err != nil {
+		return
+	}
+	request, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/flights/aircraft", c.baseURL))
 	if err != nil {
 		return
 	}
@@ -267,26 +472,87 @@ func (c *Client) GetFlightsByAircraft(icao24 string, begin time.Time, end time.T
 	if !end.IsZero() {
 		q.Set("end", fmt.Sprintf("%v", end.Unix()))
 	}
-	if icao24 != "" {
-		q.Set("icao24", icao24)
+	q.Set("icao24", icao24)
+	request.URL.RawQuery = q.Encode()
+	// Fetch response
+	err = c.doHTTP(request, &flights)
+	return c.handleFlightsNotFound(flights, err)
+}
+
+// Retrieves flights for a certain airport, identified by the ICAO code parameter, which arrived
+// within a certain time interval.
+// Flights arrived within the [begin, end] boundaries will be returned.
+// The interval must not span more than 7 days.
+//
+// If no flights were found for the given time period, an error matching ErrNotFound will be
+// returned instead, unless the client was created with WithEmptyFlightsOnNotFound.
+func (c *Client) GetArrivalsByAirport(airport string, begin time.Time, end time.Time) (flights []Flight, err error) {
+	return c.GetArrivalsByAirportContext(context.Background(), airport, begin, end)
+}
+
+// Same as GetArrivalsByAirport, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetArrivalsByAirportContext(ctx context.Context, airport string, begin time.Time, end time.Time) (flights []Flight, err error) {
+	return c.getAirportFlights(ctx, "arrival", airport, begin, end)
+}
+
+// Retrieves flights for a certain airport, identified by the ICAO code parameter, which departed
+// within a certain time interval.
+// Flights departed within the [begin, end] boundaries will be returned.
+// The interval must not span more than 7 days.
+//
+// If no flights were found for the given time period, an error matching ErrNotFound will be
+// returned instead, unless the client was created with WithEmptyFlightsOnNotFound.
+func (c *Client) GetDeparturesByAirport(airport string, begin time.Time, end time.Time) (flights []Flight, err error) {
+	return c.GetDeparturesByAirportContext(context.Background(), airport, begin, end)
+}
+
+// Same as GetDeparturesByAirport, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetDeparturesByAirportContext(ctx context.Context, airport string, begin time.Time, end time.Time) (flights []Flight, err error) {
+	return c.getAirportFlights(ctx, "departure", airport, begin, end)
+}
+
+// Shared implementation for the /flights/arrival and /flights/departure endpoints.
+// The kind parameter is the last path element of the endpoint.
+func (c *Client) getAirportFlights(ctx context.Context, kind string, airport string, begin time.Time, end time.Time) (flights []Flight, err error) {
+	var v validator
+	airport = v.airport(airport)
+	v.interval(begin, end, maxAirportFlightsInterval, true)
+	if err = v.err(); err != nil {
+		return
+	}
+	request, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/flights/%s", c.baseURL, kind))
+	if err != nil {
+		return
 	}
+	q := request.URL.Query()
+	q.Set("airport", airport)
+	q.Set("begin", fmt.Sprintf("%v", begin.Unix()))
+	q.Set("end", fmt.Sprintf("%v", end.Unix()))
 	request.URL.RawQuery = q.Encode()
 	// Fetch response
 	err = c.doHTTP(request, &flights)
-	return
+	return c.handleFlightsNotFound(flights, err)
+}
+
+// Replaces a not found error of a flight query with an empty result, if the client is configured to do so.
+func (c *Client) handleFlightsNotFound(flights []Flight, err error) ([]Flight, error) {
+	if c.emptyFlightsOnNotFound && errors.Is(err, ErrNotFound) {
+		return []Flight{}, nil
+	}
+	return flights, err
 }
 
 // Parse a single state array from an unstructured states response.
 // The i parameter represents the index of the state element in the states response.
 func parseState(s []interface{}, i int) (state State, err error) {
 	if len(s) < 17 {
-		err = fmt.Errorf("invalid state object at position %v: response contains %v values, expected 17", i, len(s))
+		err = &StateParseError{Index: i, Value: s, Err: fmt.Errorf("response contains %v values, expected 17 or 18", len(s))}
 		return
 	}
 	// icao24
 	icao24, ok := s[0].(string)
 	if !ok {
-		err = fmt.Errorf("invalid icao24 value at position %d: %v", i, s[0])
+		err = &StateParseError{Index: i, Field: "icao24", Value: s[0]}
 		return
 	}
 	// callsign
@@ -294,14 +560,14 @@ func parseState(s []interface{}, i int) (state State, err error) {
 	if s[1] != nil {
 		callsign, ok = s[1].(string)
 		if !ok {
-			err = fmt.Errorf("invalid callsign value at position %d: %v", i, s[1])
+			err = &StateParseError{Index: i, Field: "callsign", Value: s[1]}
 			return
 		}
 	}
 	// origin_country
 	originCountry, ok := s[2].(string)
 	if !ok {
-		err = fmt.Errorf("invalid origin_country value at position %d: %v", i, s[2])
+		err = &StateParseError{Index: i, Field: "origin_country", Value: s[2]}
 		return
 	}
 	// time_position
@@ -310,7 +576,7 @@ func parseState(s []interface{}, i int) (state State, err error) {
 	if s[3] != nil {
 		rawTimePosition, err = jsonNumberToInt(s[3])
 		if err != nil {
-			err = fmt.Errorf("invalid time_position value at position %d: %w", i, err)
+			err = &StateParseError{Index: i, Field: "time_position", Value: s[3], Err: err}
 			return
 		}
 		unixTime := newUnixTime(rawTimePosition)
@@ -320,81 +586,69 @@ func parseState(s []interface{}, i int) (state State, err error) {
 	var lastContact int64
 	lastContact, err = jsonNumberToInt(s[4])
 	if err != nil {
-		err = fmt.Errorf("invalid last_contact value at position %d: %w", i, err)
+		err = &StateParseError{Index: i, Field: "last_contact", Value: s[4], Err: err}
 		return
 	}
 	// longitude
-	var lon *float64
-	if rawLon, ok := s[5].(float64); ok {
-		lon = &rawLon
-	}
+	lon := jsonNumberToFloat(s[5])
 	// latitude
-	var lat *float64
-	if rawLat, ok := s[6].(float64); ok {
-		lat = &rawLat
-	}
+	lat := jsonNumberToFloat(s[6])
 	// baro_altitude
-	var baroAltitude *float64
-	if rawBaroAltitude, ok := s[7].(float64); ok {
-		baroAltitude = &rawBaroAltitude
-	}
+	baroAltitude := jsonNumberToFloat(s[7])
 	// on_ground
 	onGround, ok := s[8].(bool)
 	if !ok {
-		err = fmt.Errorf("invalid on_ground value at position %d: %v", i, s[8])
+		err = &StateParseError{Index: i, Field: "on_ground", Value: s[8]}
 		return
 	}
 	// velocity
-	var velocity *float64
-	if rawVelocity, ok := s[9].(float64); ok {
-		velocity = &rawVelocity
-	}
+	velocity := jsonNumberToFloat(s[9])
 	// true_track
-	var trueTrack *float64
-	if rawTrueTrack, ok := s[10].(float64); ok {
-		trueTrack = &rawTrueTrack
-	}
+	trueTrack := jsonNumberToFloat(s[10])
 	// vertical_rate
-	var verticalRate *float64
-	if rawVerticalRate, ok := s[11].(float64); ok {
-		verticalRate = &rawVerticalRate
-	}
+	verticalRate := jsonNumberToFloat(s[11])
 	// sensors
 	var sensors []int
 	if s[12] != nil {
 		sensors, err = jsonNumberArrayToIntArray(s[12])
 		if err != nil {
-			err = fmt.Errorf("invalid sensors value at position %d: %w", i, err)
+			err = &StateParseError{Index: i, Field: "sensors", Value: s[12], Err: err}
 			return
 		}
 	}
 	// geo_altitude
-	var geoAltitude *float64
-	if rawGeoAltitude, ok := s[13].(float64); ok {
-		geoAltitude = &rawGeoAltitude
-	}
+	geoAltitude := jsonNumberToFloat(s[13])
 	// squawk
 	var squawk string
 	if s[14] != nil {
 		squawk, ok = s[14].(string)
 		if !ok {
-			err = fmt.Errorf("invalid squawk value at position %d: %v", i, s[14])
+			err = &StateParseError{Index: i, Field: "squawk", Value: s[14]}
 			return
 		}
 	}
 	// spi
 	spi, ok := s[15].(bool)
 	if !ok {
-		err = fmt.Errorf("invalid spi value at position %d: %v", i, s[15])
+		err = &StateParseError{Index: i, Field: "spi", Value: s[15]}
 		return
 	}
 	// position_source
 	var positionSource int64
 	positionSource, err = jsonNumberToInt(s[16])
 	if err != nil {
-		err = fmt.Errorf("invalid position_source value at position %d: %w", i, err)
+		err = &StateParseError{Index: i, Field: "position_source", Value: s[16], Err: err}
 		return
 	}
+	// category (extended state vectors only)
+	var category int64
+	if len(s) > 17 && s[17] != nil {
+		category, err = jsonNumberToInt(s[17])
+		if err != nil {
+			err = &StateParseError{Index: i, Field: "category", Value: s[17], Err: err}
+			return
+		}
+	}
 	// Set state values
 	state = State{
 		ICAO24:             icao24,
@@ -414,18 +668,28 @@ func parseState(s []interface{}, i int) (state State, err error) {
 		Squawk:             squawk,
 		Spi:                spi,
 		PositionSource:     PositionSource(positionSource),
+		Category:           AircraftCategory(category),
 	}
 	return
 }
 
 // Parses an unstructured state response.
-func parseStatesResponse(rawResponse unstructuredStateResponse) (response GetStatesResponse, err error) {
+//
+// In lenient mode, invalid state arrays are skipped and collected in the Errors field of the
+// response. Otherwise, the first invalid state array aborts parsing with an error.
+func parseStatesResponse(rawResponse unstructuredStateResponse, lenient bool) (response GetStatesResponse, err error) {
 	response.Time = time.Unix(rawResponse.Time, 0)
 	// Parse state vectors
 	for i, s := range rawResponse.States {
 		var state State
 		state, err = parseState(s, i)
 		if err != nil {
+			var parseErr *StateParseError
+			if lenient && errors.As(err, &parseErr) {
+				response.Errors = append(response.Errors, parseErr)
+				err = nil
+				continue
+			}
 			return
 		}
 		// Add state
@@ -435,27 +699,66 @@ func parseStatesResponse(rawResponse unstructuredStateResponse) (response GetSta
 }
 
 // Helper function to convert a number received in a json object to an int64 type.
+// Both float64 and json.Number values are accepted. Fractional values are truncated.
 // Throws an error, if the number could not be parsed.
 func jsonNumberToInt(val interface{}) (i int64, err error) {
-	fVal, ok := val.(float64)
-	if !ok {
+	switch v := val.(type) {
+	case float64:
+		i = int64(v)
+	case json.Number:
+		if i, err = v.Int64(); err == nil {
+			return
+		}
+		// Integer values may still be encoded as floats, e.g. 1.624891429e9
+		var fVal float64
+		fVal, err = v.Float64()
+		if err != nil {
+			err = fmt.Errorf("couldn't parse %v as number", val)
+			return
+		}
+		i = int64(fVal)
+	default:
 		err = fmt.Errorf("couldn't parse %v as number", val)
-		return
 	}
-	i = int64(fVal)
 	return
 }
 
+// Helper function to convert a nullable number received in a json object to a *float64 type.
+// Both float64 and json.Number values are accepted. Returns nil for any other value.
+func jsonNumberToFloat(val interface{}) *float64 {
+	switch v := val.(type) {
+	case float64:
+		return &v
+	case json.Number:
+		if fVal, err := v.Float64(); err == nil {
+			return &fVal
+		}
+	}
+	return nil
+}
+
 // Helper function to convert a number array received in a json object to an []int type.
+// Both decoded JSON arrays ([]interface{}) and []float64 values are accepted.
 // Throws an error, if the value could not be parsed as a number array.
 func jsonNumberArrayToIntArray(val interface{}) (a []int, err error) {
-	aVal, ok := val.([]float64)
-	if !ok {
+	switch v := val.(type) {
+	case []float64:
+		for _, f := range v {
+			a = append(a, int(f))
+		}
+	case []interface{}:
+		for _, e := range v {
+			var i int64
+			i, err = jsonNumberToInt(e)
+			if err != nil {
+				a = nil
+				err = fmt.Errorf("couldn't parse %v as number array: %w", val, err)
+				return
+			}
+			a = append(a, int(i))
+		}
+	default:
 		err = fmt.Errorf("couldn't parse %v as number array", val)
-		return
-	}
-	for _, v := range aVal {
-		a = append(a, int(v))
 	}
 	return
 }
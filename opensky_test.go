@@ -1,7 +1,11 @@
 package opensky
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -40,6 +44,89 @@ func TestUnmarshalUnixTime(t *testing.T) {
 	}
 }
 
+func TestMarshalUnixTime(t *testing.T) {
+	type wrapper struct {
+		Time    UnixTime  `json:"time"`
+		Pointer *UnixTime `json:"pointer,omitempty"`
+	}
+	type testCase struct {
+		value              wrapper
+		expectedJSONString string
+	}
+	cases := []testCase{
+		{wrapper{Time: newUnixTime(1624891429)}, `{"time":1624891429}`},
+		{wrapper{Time: newUnixTime(0)}, `{"time":0}`},
+		{wrapper{Time: newUnixTime(-1)}, `{"time":-1}`},
+		{wrapper{}, `{"time":null}`},
+		{wrapper{Pointer: newUnixTimeP(1624891429)}, `{"time":null,"pointer":1624891429}`},
+		{wrapper{Pointer: &UnixTime{}}, `{"time":null,"pointer":null}`},
+	}
+	for _, c := range cases {
+		b, err := json.Marshal(c.value)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expectedJSONString, string(b))
+		// Round trip
+		var w wrapper
+		assert.NoError(t, json.Unmarshal(b, &w))
+		assert.Equal(t, c.value.Time, w.Time)
+		if c.value.Pointer != nil && !c.value.Pointer.IsZero() {
+			assert.Equal(t, c.value.Pointer, w.Pointer)
+		}
+	}
+}
+
+func TestUnixTimeText(t *testing.T) {
+	type testCase struct {
+		text          string
+		expectedTime  UnixTime
+		expectedError bool
+	}
+	cases := []testCase{
+		{"1624891429", newUnixTime(1624891429), false},
+		{"0", newUnixTime(0), false},
+		{"-1", newUnixTime(-1), false},
+		{"", UnixTime{}, false},
+		{"string", UnixTime{}, true},
+		{"1624891429.5", UnixTime{}, true},
+	}
+	for _, c := range cases {
+		var u UnixTime
+		err := u.UnmarshalText([]byte(c.text))
+		if c.expectedError {
+			assert.Error(t, err, c.text)
+			continue
+		}
+		assert.NoError(t, err, c.text)
+		assert.Equal(t, c.expectedTime, u, c.text)
+		// Round trip
+		b, err := u.MarshalText()
+		assert.NoError(t, err)
+		assert.Equal(t, c.text, string(b))
+	}
+}
+
+func TestFlightJSONRoundTrip(t *testing.T) {
+	flight := Flight{
+		ICAO24:              "3c6444",
+		FirstSeen:           newUnixTime(1517227113),
+		EstDepartureAirport: "EDDF",
+		LastSeen:            newUnixTime(1517230737),
+		CallSign:            "DLH9LF  ",
+	}
+	b, err := json.Marshal(flight)
+	assert.NoError(t, err)
+	var decoded Flight
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, flight, decoded)
+	// Zero times are encoded as null
+	b, err = json.Marshal(Flight{ICAO24: "3c6444"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"firstSeen":null`)
+	decoded = Flight{}
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, Flight{ICAO24: "3c6444"}, decoded)
+}
+
 func TestJsonNumberToInt(t *testing.T) {
 	type testCase struct {
 		value         interface{}
@@ -54,6 +141,11 @@ func TestJsonNumberToInt(t *testing.T) {
 		{"foo", 0, true},
 		{true, 0, true},
 		{[]float64{1, 3, 5}, 0, true},
+		{json.Number("1624891429"), 1624891429, false},
+		{json.Number("1.624891429e9"), 1624891429, false},
+		{json.Number("-3.7"), -3, false},
+		{json.Number("foo"), 0, true},
+		{nil, 0, true},
 	}
 	for _, c := range cases {
 		i, err := jsonNumberToInt(c.value)
@@ -79,6 +171,11 @@ func TestJsonNumberArrayToIntArray(t *testing.T) {
 		{[]int{1, 2, 100, -100}, nil, true},
 		{"foo", nil, true},
 		{true, nil, true},
+		{[]interface{}{float64(1000), float64(1042)}, []int{1000, 1042}, false},
+		{[]interface{}{json.Number("1000"), json.Number("1042.0")}, []int{1000, 1042}, false},
+		{[]interface{}{}, nil, false},
+		{[]interface{}{json.Number("1000"), "1042"}, nil, true},
+		{[]interface{}{nil}, nil, true},
 	}
 	for _, c := range cases {
 		i, err := jsonNumberArrayToIntArray(c.value)
@@ -91,6 +188,25 @@ func TestJsonNumberArrayToIntArray(t *testing.T) {
 	}
 }
 
+func TestJsonNumberToFloat(t *testing.T) {
+	type testCase struct {
+		value         interface{}
+		expectedValue *float64
+	}
+	cases := []testCase{
+		{42.5, newFloat(42.5)},
+		{json.Number("-116.2121"), newFloat(-116.2121)},
+		{json.Number("914"), newFloat(914)},
+		{json.Number("foo"), nil},
+		{nil, nil},
+		{"43.5431", nil},
+		{true, nil},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expectedValue, jsonNumberToFloat(c.value))
+	}
+}
+
 func TestParseState(t *testing.T) {
 	type testCase struct {
 		raw            []interface{}
@@ -728,6 +844,142 @@ func TestParseState(t *testing.T) {
 	}
 }
 
+func TestParseExtendedState(t *testing.T) {
+	type testCase struct {
+		raw            []interface{}
+		expectedResult State
+		expectedError  bool
+	}
+	base := func(category interface{}) []interface{} {
+		return []interface{}{
+			"a50c7c",
+			nil,
+			"United States",
+			float64(1624891429),
+			float64(1624891429),
+			nil,
+			nil,
+			nil,
+			false,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			false,
+			float64(0),
+			category,
+		}
+	}
+	expected := func(category AircraftCategory) State {
+		return State{
+			ICAO24:         "a50c7c",
+			OriginCountry:  "United States",
+			TimePosition:   newUnixTimeP(1624891429),
+			LastContact:    newUnixTime(1624891429),
+			PositionSource: ADSB,
+			Category:       category,
+		}
+	}
+	cases := []testCase{
+		// category is set -> OK
+		{base(float64(8)), expected(CategoryRotorcraft), false},
+		// category is nil -> OK
+		{base(nil), expected(CategoryNoInformation), false},
+		// category is invalid -> Error
+		{base("invalid_category"), State{}, true},
+	}
+	for i, c := range cases {
+		state, err := parseState(c.raw, i)
+		assert.Equal(t, c.expectedResult, state)
+		if c.expectedError {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	type testCase struct {
+		bbox            BoundingBox
+		lat             float64
+		lon             float64
+		expectedCrosses bool
+		expectedInside  bool
+		expectedParts   int
+	}
+	europe := BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}
+	pacific := BoundingBox{LatMin: -20, LonMin: 170, LatMax: 20, LonMax: -170}
+	cases := []testCase{
+		{europe, 47, 8, false, true, 1},
+		{europe, 45, 5, false, true, 1},
+		{europe, 50, 10, false, true, 1},
+		{europe, 47, 11, false, false, 1},
+		{europe, 51, 8, false, false, 1},
+		{pacific, 0, 175, true, true, 2},
+		{pacific, 0, 180, true, true, 2},
+		{pacific, 0, -180, true, true, 2},
+		{pacific, 0, -175, true, true, 2},
+		{pacific, 0, 170, true, true, 2},
+		{pacific, 0, -170, true, true, 2},
+		{pacific, 0, 0, true, false, 2},
+		{pacific, 0, 169, true, false, 2},
+		{pacific, 0, -169, true, false, 2},
+		{pacific, 21, 180, true, false, 2},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expectedCrosses, c.bbox.CrossesAntimeridian(), "%+v", c.bbox)
+		assert.Equal(t, c.expectedInside, c.bbox.Contains(c.lat, c.lon), "%+v %v %v", c.bbox, c.lat, c.lon)
+		parts := c.bbox.Split()
+		assert.Len(t, parts, c.expectedParts)
+		// The parts cover the same area as the box
+		inside := false
+		for _, part := range parts {
+			assert.False(t, part.CrossesAntimeridian())
+			inside = inside || part.Contains(c.lat, c.lon)
+		}
+		assert.Equal(t, c.expectedInside, inside, "%+v %v %v", c.bbox, c.lat, c.lon)
+	}
+	assert.Equal(t, []BoundingBox{
+		{LatMin: -20, LonMin: 170, LatMax: 20, LonMax: 180},
+		{LatMin: -20, LonMin: -180, LatMax: 20, LonMax: -170},
+	}, pacific.Split())
+	assert.Equal(t, 800.0, pacific.Area())
+}
+
+func TestAircraftCategoryString(t *testing.T) {
+	assert.Equal(t, "No information", CategoryNoInformation.String())
+	assert.Equal(t, "Heavy", CategoryHeavy.String())
+	assert.Equal(t, "Rotorcraft", CategoryRotorcraft.String())
+	assert.Equal(t, "Unmanned aerial vehicle", CategoryUAV.String())
+	assert.Equal(t, "Line obstacle", CategoryLineObstacle.String())
+	assert.Equal(t, "AircraftCategory(21)", AircraftCategory(21).String())
+	assert.Equal(t, "AircraftCategory(-1)", AircraftCategory(-1).String())
+}
+
+func TestGetExtendedStates(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[["a50c7c",null,"United States",1624891429,1624891429,null,null,null,false,null,null,null,null,null,null,false,0,6]]}`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	response, err := client.GetExtendedStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, response.States, 1) {
+		assert.Equal(t, CategoryHeavy, response.States[0].Category)
+	}
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "1", requests[0].URL.Query().Get("extended"))
+		assert.Equal(t, "", requests[1].URL.Query().Get("extended"))
+	}
+}
+
 func TestParseStatesResponse(t *testing.T) {
 	type testCase struct {
 		raw            unstructuredStateResponse
@@ -860,7 +1112,7 @@ func TestParseStatesResponse(t *testing.T) {
 	}
 	// Run tests
 	for _, c := range cases {
-		result, err := parseStatesResponse(c.raw)
+		result, err := parseStatesResponse(c.raw, false)
 		assert.Equal(t, c.expectedResult, result)
 		if c.expectedError {
 			assert.Error(t, err)
@@ -870,6 +1122,92 @@ func TestParseStatesResponse(t *testing.T) {
 	}
 }
 
+func TestParseStatesResponseLenient(t *testing.T) {
+	valid := []interface{}{
+		"a50c7c",
+		nil,
+		"United States",
+		float64(1624891429),
+		float64(1624891429),
+		nil,
+		nil,
+		nil,
+		false,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		false,
+		float64(0),
+	}
+	invalidSpi := make([]interface{}, len(valid))
+	copy(invalidSpi, valid)
+	invalidSpi[15] = "invalid_spi"
+	invalidTime := make([]interface{}, len(valid))
+	copy(invalidTime, valid)
+	invalidTime[4] = "invalid_time"
+	truncated := valid[:3]
+	raw := unstructuredStateResponse{Time: 1624958210, States: [][]interface{}{invalidSpi, valid, truncated, invalidTime, valid}}
+	expectedState := State{
+		ICAO24:         "a50c7c",
+		OriginCountry:  "United States",
+		TimePosition:   newUnixTimeP(1624891429),
+		LastContact:    newUnixTime(1624891429),
+		PositionSource: ADSB,
+	}
+	// Strict mode fails on the first invalid state
+	_, err := parseStatesResponse(raw, false)
+	var parseErr *StateParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, 0, parseErr.Index)
+		assert.Equal(t, "spi", parseErr.Field)
+	}
+	// Lenient mode skips invalid states
+	result, err := parseStatesResponse(raw, true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1624958210, 0), result.Time)
+	assert.Equal(t, []State{expectedState, expectedState}, result.States)
+	if assert.Len(t, result.Errors, 3) {
+		assert.Equal(t, 0, result.Errors[0].Index)
+		assert.Equal(t, "spi", result.Errors[0].Field)
+		assert.Equal(t, "invalid_spi", result.Errors[0].Value)
+		assert.Nil(t, result.Errors[0].Err)
+		assert.Equal(t, "invalid spi value at position 0: invalid_spi", result.Errors[0].Error())
+		assert.Equal(t, 2, result.Errors[1].Index)
+		assert.Equal(t, "", result.Errors[1].Field)
+		assert.Equal(t, truncated, result.Errors[1].Value)
+		assert.Equal(t, "invalid state object at position 2: response contains 3 values, expected 17 or 18", result.Errors[1].Error())
+		assert.Equal(t, 3, result.Errors[2].Index)
+		assert.Equal(t, "last_contact", result.Errors[2].Field)
+		assert.Equal(t, "invalid_time", result.Errors[2].Value)
+		assert.Error(t, result.Errors[2].Err)
+		assert.Equal(t, "invalid last_contact value at position 3: couldn't parse invalid_time as number", result.Errors[2].Error())
+	}
+}
+
+func TestGetStatesLenient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[` +
+			`["a50c7c",null,"United States",1624891429,1624891429,null,null,null,false,null,null,null,null,null,null,false,0],` +
+			`["ae1fa7",null,"United States",1624891429,1624891429,null,null,null,"yes",null,null,null,null,null,null,false,0]]}`))
+	}))
+	defer server.Close()
+	// Strict by default
+	_, err := NewClient("", "", WithBaseURL(server.URL)).GetStates(time.Time{}, nil, nil)
+	assert.Error(t, err)
+	// Lenient on request
+	response, err := NewClient("", "", WithBaseURL(server.URL), WithLenientParsing()).GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, response.States, 1) {
+		assert.Equal(t, "a50c7c", response.States[0].ICAO24)
+	}
+	if assert.Len(t, response.Errors, 1) {
+		assert.Equal(t, &StateParseError{Index: 1, Field: "on_ground", Value: "yes"}, response.Errors[0])
+	}
+}
+
 func BenchmarkParseStatesResponse(b *testing.B) {
 	rawResponse := unstructuredStateResponse{Time: 1624958210, States: [][]interface{}{
 		{
@@ -911,7 +1249,219 @@ func BenchmarkParseStatesResponse(b *testing.B) {
 			float64(0),
 		},
 	}}
-	_, _ = parseStatesResponse(rawResponse)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = parseStatesResponse(rawResponse, false)
+	}
+}
+
+func TestGetAirportFlights(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(`[{"icao24":"3c6444","firstSeen":1624860000,"estDepartureAirport":"EDDF","lastSeen":1624870000,"estArrivalAirport":"EGLL","callsign":"DLH900  "}]`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	begin := time.Unix(1624860000, 0)
+	end := begin.Add(2 * time.Hour)
+	expected := []Flight{
+		{
+			ICAO24:              "3c6444",
+			FirstSeen:           newUnixTime(1624860000),
+			EstDepartureAirport: "EDDF",
+			LastSeen:            newUnixTime(1624870000),
+			EstArrivalAirport:   "EGLL",
+			CallSign:            "DLH900  ",
+		},
+	}
+	// Arrivals
+	flights, err := client.GetArrivalsByAirport("egll", begin, end)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, flights)
+	// Departures
+	flights, err = client.GetDeparturesByAirport("EDDF", begin, end)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, flights)
+	// Check rendered requests
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "/flights/arrival", requests[0].URL.Path)
+		assert.Equal(t, "EGLL", requests[0].URL.Query().Get("airport"))
+		assert.Equal(t, "1624860000", requests[0].URL.Query().Get("begin"))
+		assert.Equal(t, "1624867200", requests[0].URL.Query().Get("end"))
+		assert.Equal(t, "/flights/departure", requests[1].URL.Path)
+		assert.Equal(t, "EDDF", requests[1].URL.Query().Get("airport"))
+	}
+}
+
+func TestGetAirportFlightsValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %v", r.URL)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	type testCase struct {
+		airport string
+		begin   time.Time
+		end     time.Time
+	}
+	begin := time.Unix(1624860000, 0)
+	cases := []testCase{
+		{"", begin, begin.Add(time.Hour)},
+		{"EDD", begin, begin.Add(time.Hour)},
+		{"EDDFX", begin, begin.Add(time.Hour)},
+		{"ED-F", begin, begin.Add(time.Hour)},
+		{"EDDF", time.Time{}, begin},
+		{"EDDF", begin, time.Time{}},
+		{"EDDF", begin, begin},
+		{"EDDF", begin.Add(time.Hour), begin},
+		{"EDDF", begin, begin.Add(7*24*time.Hour + time.Second)},
+	}
+	for _, c := range cases {
+		_, err := client.GetArrivalsByAirport(c.airport, c.begin, c.end)
+		assert.Error(t, err)
+		_, err = client.GetDeparturesByAirport(c.airport, c.begin, c.end)
+		assert.Error(t, err)
+	}
+}
+
+func TestGetAirportFlightsNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	begin := time.Unix(1624860000, 0)
+	flights, err := client.GetArrivalsByAirport("EDDF", begin, begin.Add(7*24*time.Hour))
+	assert.Error(t, err)
+	assert.Nil(t, flights)
+}
+
+func TestContextCancellation(t *testing.T) {
+	released := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Block until the client gives up or the test ends
+		select {
+		case <-r.Context().Done():
+		case <-released:
+		}
+	}))
+	defer server.Close()
+	defer close(released)
+	client := NewClient("", "", WithBaseURL(server.URL))
+	begin := time.Unix(1624860000, 0)
+	end := begin.Add(time.Hour)
+	calls := map[string]func(ctx context.Context) error{
+		"GetStatesContext": func(ctx context.Context) error {
+			_, err := client.GetStatesContext(ctx, time.Time{}, nil, nil)
+			return err
+		},
+		"GetExtendedStatesContext": func(ctx context.Context) error {
+			_, err := client.GetExtendedStatesContext(ctx, time.Time{}, nil, nil)
+			return err
+		},
+		"GetOwnStatesContext": func(ctx context.Context) error {
+			_, err := client.GetOwnStatesContext(ctx, time.Time{}, nil, nil)
+			return err
+		},
+		"GetFlightsContext": func(ctx context.Context) error {
+			_, err := client.GetFlightsContext(ctx, begin, end)
+			return err
+		},
+		"GetFlightsByAircraftContext": func(ctx context.Context) error {
+			_, err := client.GetFlightsByAircraftContext(ctx, "a50c7c", begin, end)
+			return err
+		},
+		"GetArrivalsByAirportContext": func(ctx context.Context) error {
+			_, err := client.GetArrivalsByAirportContext(ctx, "EDDF", begin, end)
+			return err
+		},
+		"GetDeparturesByAirportContext": func(ctx context.Context) error {
+			_, err := client.GetDeparturesByAirportContext(ctx, "EDDF", begin, end)
+			return err
+		},
+		"GetTrackContext": func(ctx context.Context) error {
+			_, err := client.GetTrackContext(ctx, "a50c7c", time.Time{})
+			return err
+		},
+	}
+	for name, call := range calls {
+		// Deadline expires while the request is pending
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := call(ctx)
+		cancel()
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "%s: unexpected error %v", name, err)
+		// Context is cancelled before the request is sent
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		err = call(ctx)
+		assert.True(t, errors.Is(err, context.Canceled), "%s: unexpected error %v", name, err)
+	}
+}
+
+func TestDecodeStatesPayload(t *testing.T) {
+	// Payloads as returned by /states/own and /states/all
+	payloads := map[string]string{
+		"/states/own": `{"time":1624958210,"states":[` +
+			`["ae1fa7","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,false,17.95,117.3,-1.3,[1000,1042],952.5,"0753",false,0],` +
+			`["3c6444","DLH900  ","Germany",1.624891429E9,1624891430,8.5622,50.0379,null,true,0,249,null,[1042],null,null,false,2]]}`,
+		"/states/all": `{"time":1624958210,"states":[` +
+			`["a50c7c",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(payloads[r.URL.Path]))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	// Own states always contain sensors
+	response, err := client.GetOwnStates(time.Time{}, nil, []int{1000, 1042})
+	assert.NoError(t, err)
+	assert.Equal(t, GetStatesResponse{Time: time.Unix(1624958210, 0), States: []State{
+		{
+			ICAO24:             "ae1fa7",
+			CallSign:           "TALON71 ",
+			OriginCountry:      "United States",
+			TimePosition:       newUnixTimeP(1624891429),
+			LastContact:        newUnixTime(1624891429),
+			Longitude:          newFloat(-116.2121),
+			Latitude:           newFloat(43.5431),
+			BarometricAltitude: newFloat(914.4),
+			OnGround:           false,
+			Velocity:           newFloat(17.95),
+			Heading:            newFloat(117.3),
+			VerticalRate:       newFloat(-1.3),
+			Sensors:            []int{1000, 1042},
+			GeoAltitude:        newFloat(952.5),
+			Squawk:             "0753",
+			Spi:                false,
+			PositionSource:     ADSB,
+		},
+		{
+			ICAO24:         "3c6444",
+			CallSign:       "DLH900  ",
+			OriginCountry:  "Germany",
+			TimePosition:   newUnixTimeP(1624891429),
+			LastContact:    newUnixTime(1624891430),
+			Longitude:      newFloat(8.5622),
+			Latitude:       newFloat(50.0379),
+			OnGround:       true,
+			Velocity:       newFloat(0),
+			Heading:        newFloat(249),
+			Sensors:        []int{1042},
+			PositionSource: MLAT,
+		},
+	}}, response)
+	// All states without optional values
+	response, err = client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, GetStatesResponse{Time: time.Unix(1624958210, 0), States: []State{
+		{
+			ICAO24:         "a50c7c",
+			OriginCountry:  "United States",
+			LastContact:    newUnixTime(1624891429),
+			PositionSource: ADSB,
+		},
+	}}, response)
 }
 
 func TestApi(t *testing.T) {
@@ -0,0 +1,126 @@
+package openskytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	opensky "github.com/ororatech/go-opensky-api"
+)
+
+// Faults simulated by a Server, to test how callers cope with an API under stress.
+type Faults struct {
+	Latency           time.Duration // Delay before every response.
+	ServerErrors      int           // Number of consecutive requests answered with ServerErrorStatus, starting with the next one.
+	ServerErrorStatus int           // Status code of simulated server errors. Defaults to 503.
+	TruncatedBodies   int           // Number of successful responses, starting with the next one, whose body is cut off in the middle.
+	MalformedRows     int           // Number of malformed rows appended to the states of every state vectors response.
+}
+
+// Simulates the passed faults, as if set with SetFaults.
+func WithFaults(faults Faults) Option {
+	return func(s *Server) {
+		s.setFaults(faults)
+	}
+}
+
+// Replaces the simulated faults. The counts of server errors and truncated bodies start over.
+func (s *Server) SetFaults(faults Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setFaults(faults)
+}
+
+// Replaces the simulated faults. Must be called with the lock held.
+func (s *Server) setFaults(faults Faults) {
+	if faults.ServerErrorStatus == 0 {
+		faults.ServerErrorStatus = http.StatusServiceUnavailable
+	}
+	s.faults = faults
+	s.failures = faults.ServerErrors
+	s.truncations = faults.TruncatedBodies
+}
+
+// API credit budget of a single user.
+type creditAccount struct {
+	remaining  int
+	retryAfter time.Duration
+}
+
+// Limits the API credits of a user, as if set with SetCredits.
+func WithCredits(username string, credits int, retryAfter time.Duration) Option {
+	return func(s *Server) {
+		s.credits[username] = &creditAccount{remaining: credits, retryAfter: retryAfter}
+	}
+}
+
+// Limits the API credits of a user, or of anonymous requests if username is empty.
+//
+// Every /states/all request of the user is charged like by OpenSky, see opensky.StatesCreditCost,
+// and the remaining credits are sent in the X-Rate-Limit-Remaining header. Once the credits are
+// exhausted, requests are answered with 429 Too Many Requests, and the retryAfter duration is sent
+// in the X-Rate-Limit-Retry-After-Seconds and Retry-After headers.
+//
+// Users without a limit have unlimited credits.
+func (s *Server) SetCredits(username string, credits int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credits[username] = &creditAccount{remaining: credits, retryAfter: retryAfter}
+}
+
+// Returns the remaining API credits of a user, or of anonymous requests if username is empty.
+// The ok result is false, if the credits of the user are not limited.
+func (s *Server) Credits(username string) (remaining int, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	account, ok := s.credits[username]
+	if !ok {
+		return 0, false
+	}
+	return account.remaining, true
+}
+
+// Charges a state vectors query to the credits of the user, if they are limited, and sets the
+// rate limit headers. Returns a 429 error, if the credits are exhausted.
+// Must be called with the lock held.
+func (s *Server) chargeCredits(username string, q url.Values, header http.Header) error {
+	account, ok := s.credits[username]
+	if !ok {
+		return nil
+	}
+	bbox, err := parseBoundingBox(q)
+	if err != nil {
+		return err
+	}
+	cost := opensky.StatesCreditCost(bbox)
+	if cost > account.remaining {
+		retryAfter := strconv.Itoa(int(account.retryAfter / time.Second))
+		header.Set("X-Rate-Limit-Remaining", strconv.Itoa(account.remaining))
+		header.Set("X-Rate-Limit-Retry-After-Seconds", retryAfter)
+		header.Set("Retry-After", retryAfter)
+		return &httpError{status: http.StatusTooManyRequests, message: "Too many requests"}
+	}
+	account.remaining -= cost
+	header.Set("X-Rate-Limit-Remaining", strconv.Itoa(account.remaining))
+	return nil
+}
+
+// Appends malformed rows to the states of an encoded state vectors response.
+// Each row has a valid length, but an invalid on_ground value.
+func addMalformedRows(body []byte, n int) ([]byte, error) {
+	var raw struct {
+		Time   *int64            `json:"time"`
+		States []json.RawMessage `json:"states"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		row := fmt.Sprintf(`["%06x",null,"Malformed",null,0,null,null,null,"invalid",null,null,null,null,null,null,false,0]`, 0xff0000+i)
+		raw.States = append(raw.States, json.RawMessage(row))
+	}
+	return json.Marshal(raw)
+}
@@ -0,0 +1,124 @@
+package openskytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	opensky "github.com/ororatech/go-opensky-api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredits(t *testing.T) {
+	server := NewServer(WithUser("alice", "secret"), WithCredits("alice", 10, time.Hour))
+	defer server.Close()
+	server.SetCredits("", 3, 30*time.Minute)
+	server.AddStates(time.Unix(1000, 0), newState("3c6444", 50, 8, 1000))
+	bbox := &opensky.BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}
+	// Anonymous requests
+	client := server.NewClient("", "")
+	_, err := client.GetStates(time.Time{}, nil, bbox)
+	assert.NoError(t, err)
+	if remaining := client.LastRateLimit().Remaining; assert.NotNil(t, remaining) {
+		assert.Equal(t, 2, *remaining)
+	}
+	// A global query costs 4 credits, which exceeds the remaining ones
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, opensky.ErrRateLimited))
+	var apiErr *opensky.APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, 30*time.Minute, apiErr.RateLimit.RetryAfter)
+	}
+	remaining, ok := server.Credits("")
+	assert.True(t, ok)
+	assert.Equal(t, 2, remaining)
+	// Users have their own budget
+	_, err = server.NewClient("alice", "secret").GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	remaining, ok = server.Credits("alice")
+	assert.True(t, ok)
+	assert.Equal(t, 6, remaining)
+	// Users without limit
+	server.AddUser("bob", "secret")
+	_, ok = server.Credits("bob")
+	assert.False(t, ok)
+	bobClient := server.NewClient("bob", "secret")
+	_, err = bobClient.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, bobClient.LastRateLimit().Remaining)
+}
+
+func TestServerErrorBurst(t *testing.T) {
+	server := NewServer(WithFaults(Faults{ServerErrors: 2}))
+	defer server.Close()
+	var attempts []opensky.RetryAttempt
+	client := server.NewClient("", "", opensky.WithRetryPolicy(opensky.RetryPolicy{
+		MaxAttempts: 3,
+		OnAttempt: func(attempt opensky.RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			return nil
+		},
+	}))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, attempts, 3) {
+		assert.True(t, errors.Is(attempts[0].Err, opensky.ErrServer))
+		assert.True(t, errors.Is(attempts[1].Err, opensky.ErrServer))
+		assert.NoError(t, attempts[2].Err)
+	}
+	// Custom status code
+	server.SetFaults(Faults{ServerErrors: 1, ServerErrorStatus: http.StatusBadGateway})
+	_, err = server.NewClient("", "").GetStates(time.Time{}, nil, nil)
+	var apiErr *opensky.APIError
+	if assert.True(t, errors.As(err, &apiErr)) {
+		assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	}
+	_, err = server.NewClient("", "").GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+}
+
+func TestLatency(t *testing.T) {
+	server := NewServer(WithFaults(Faults{Latency: 200 * time.Millisecond}))
+	defer server.Close()
+	_, err := server.NewClient("", "", opensky.WithTimeout(20*time.Millisecond)).GetStates(time.Time{}, nil, nil)
+	assert.Error(t, err)
+	start := time.Now()
+	_, err = server.NewClient("", "").GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 200*time.Millisecond)
+}
+
+func TestTruncatedBodies(t *testing.T) {
+	server := NewServer(WithFaults(Faults{TruncatedBodies: 1}))
+	defer server.Close()
+	server.AddStates(time.Unix(1000, 0), newState("3c6444", 50, 8, 1000), newState("a50c7c", 40, -100, 1000))
+	client := server.NewClient("", "")
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.Error(t, err)
+	response, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, response.States, 2)
+}
+
+func TestMalformedRows(t *testing.T) {
+	server := NewServer(WithFaults(Faults{MalformedRows: 2}))
+	defer server.Close()
+	server.AddStates(time.Unix(1000, 0), newState("3c6444", 50, 8, 1000))
+	_, err := server.NewClient("", "").GetStates(time.Time{}, nil, nil)
+	var parseErr *opensky.StateParseError
+	assert.True(t, errors.As(err, &parseErr))
+	response, err := server.NewClient("", "", opensky.WithLenientParsing()).GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3c6444"}, icao24s(response.States))
+	if assert.Len(t, response.Errors, 2) {
+		assert.Equal(t, "on_ground", response.Errors[0].Field)
+	}
+	// Faults can be disabled again
+	server.SetFaults(Faults{})
+	_, err = server.NewClient("", "").GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,165 @@
+package openskytest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Error returned by a Replayer, if no fixture matches a request.
+var ErrNoFixture = errors.New("openskytest: no fixture for request")
+
+// A recorded response, stored as JSON file in a fixture directory.
+type fixture struct {
+	Request string      `json:"request"` // Normalized request, see fixtureKey.
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    string      `json:"body"`
+}
+
+// An http.RoundTripper, which performs requests via another transport and stores every response as
+// fixture in a directory, to be served by a Replayer later on.
+//
+// Only the method, path and query of a request identify a fixture, hence credentials are never
+// stored. A later response to an equivalent request overwrites the earlier fixture.
+type Recorder struct {
+	dir       string
+	transport http.RoundTripper
+	mu        sync.Mutex
+}
+
+// Creates a new recorder, which writes fixtures to dir.
+// If transport is nil, http.DefaultTransport is used to perform the requests.
+func NewRecorder(dir string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{dir: dir, transport: transport}
+}
+
+// Performs the request and records its response.
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	f := fixture{
+		Request: fixtureKey(request),
+		Status:  resp.StatusCode,
+		Header:  resp.Header,
+		Body:    string(body),
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err = os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(filepath.Join(r.dir, fixtureFileName(f.Request)), data, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// An http.RoundTripper, which serves the fixtures recorded by a Recorder instead of performing
+// requests.
+//
+// Requests are matched by method, path and query parameters. The order of the parameters and of
+// comma-separated values, such as ICAO24 addresses, is irrelevant. Requests without a matching
+// fixture fail with an error matching ErrNoFixture.
+type Replayer struct {
+	fixtures map[string]fixture
+}
+
+// Creates a new replayer, serving all fixtures of the passed directory.
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{fixtures: map[string]fixture{}}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f fixture
+		if err = json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", file, err)
+		}
+		r.fixtures[f.Request] = f
+	}
+	return r, nil
+}
+
+// Serves the recorded response for the request.
+func (r *Replayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	key := fixtureKey(request)
+	f, ok := r.fixtures[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoFixture, key)
+	}
+	header := http.Header{}
+	for name, values := range f.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       request,
+	}, nil
+}
+
+// Returns the normalized representation of a request, consisting of method, path and query.
+// Query parameters are sorted by name, and their values, including comma-separated ones, by value.
+func fixtureKey(request *http.Request) string {
+	query := request.URL.Query()
+	normalized := url.Values{}
+	for name, values := range query {
+		var split []string
+		for _, v := range values {
+			split = append(split, strings.Split(v, ",")...)
+		}
+		sort.Strings(split)
+		normalized.Set(name, strings.Join(split, ","))
+	}
+	key := request.Method + " " + request.URL.Path
+	if len(normalized) > 0 {
+		key += "?" + normalized.Encode()
+	}
+	return key
+}
+
+// Returns the file name of the fixture of a normalized request, consisting of the path and a hash.
+func fixtureFileName(key string) string {
+	path := strings.SplitN(strings.SplitN(key, " ", 2)[1], "?", 2)[0]
+	name := strings.Trim(strings.Replace(path, "/", "_", -1), "_")
+	hash := sha1.Sum([]byte(key))
+	return fmt.Sprintf("%s-%s.json", name, hex.EncodeToString(hash[:6]))
+}
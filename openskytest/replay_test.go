@@ -0,0 +1,99 @@
+package openskytest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	opensky "github.com/ororatech/go-opensky-api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	server := NewServer()
+	server.AddStates(time.Unix(1000, 0), newState("3c6444", 50, 8, 1000), newState("a50c7c", 40, -100, 1000))
+	// Record
+	recorder := NewRecorder(dir, nil)
+	client := server.NewClient("", "", opensky.WithHTTPClient(&http.Client{Transport: recorder}))
+	recorded, err := client.GetStates(time.Unix(1000, 0), []string{"3c6444", "a50c7c"}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, recorded.States, 2)
+	_, err = client.GetFlights(time.Unix(0, 0), time.Unix(3600, 0))
+	assert.True(t, errors.Is(err, opensky.ErrNotFound))
+	server.Close()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	// Replay without the server
+	replayer, err := NewReplayer(dir)
+	assert.NoError(t, err)
+	client = opensky.NewClient("", "", opensky.WithBaseURL(server.URL), opensky.WithHTTPClient(&http.Client{Transport: replayer}))
+	// The order of ICAO24 addresses does not matter
+	replayed, err := client.GetStates(time.Unix(1000, 0), []string{"a50c7c", "3c6444"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+	_, err = client.GetFlights(time.Unix(0, 0), time.Unix(3600, 0))
+	assert.True(t, errors.Is(err, opensky.ErrNotFound))
+	// Requests without fixture fail
+	_, err = client.GetStates(time.Unix(2000, 0), nil, nil)
+	assert.True(t, errors.Is(err, ErrNoFixture))
+}
+
+func TestRecordHeaders(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Rate-Limit-Remaining", "42")
+		_, _ = w.Write([]byte(`{"time":1000,"states":null}`))
+	}))
+	defer server.Close()
+	httpClient := &http.Client{Transport: NewRecorder(dir, nil)}
+	resp, err := httpClient.Get(server.URL + "/states/all?lamin=1&lomin=2&lamax=3&lomax=4")
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, `{"time":1000,"states":null}`, string(body))
+	}
+	replayer, err := NewReplayer(dir)
+	assert.NoError(t, err)
+	httpClient = &http.Client{Transport: replayer}
+	resp, err = httpClient.Get("http://example.com/states/all?lomax=4&lamax=3&lomin=2&lamin=1")
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "42", resp.Header.Get("X-Rate-Limit-Remaining"))
+		assert.Equal(t, `{"time":1000,"states":null}`, string(body))
+	}
+}
+
+func TestFixtureKey(t *testing.T) {
+	type testCase struct {
+		url         string
+		expectedKey string
+	}
+	cases := []testCase{
+		{"http://localhost/states/all", "GET /states/all"},
+		{"https://opensky-network.org/api/states/all?time=1", "GET /api/states/all?time=1"},
+		{"http://localhost/states/all?icao24=b,a&time=1", "GET /states/all?icao24=a%2Cb&time=1"},
+		{"http://localhost/states/all?time=1&icao24=a&icao24=b", "GET /states/all?icao24=a%2Cb&time=1"},
+	}
+	for _, c := range cases {
+		request, err := http.NewRequest("GET", c.url, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expectedKey, fixtureKey(request))
+	}
+	assert.Regexp(t, `^states_all-[0-9a-f]{12}\.json$`, fixtureFileName("GET /states/all?time=1"))
+}
+
+func TestNewReplayerInvalidFixture(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0644))
+	_, err := NewReplayer(dir)
+	assert.Error(t, err)
+}
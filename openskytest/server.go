@@ -0,0 +1,478 @@
+// Package openskytest implements a local mock of the OpenSky REST API, so that code using the
+// opensky client can be tested end-to-end without network access.
+//
+// The server answers requests from in-memory fixtures and mimics the behavior of the real API,
+// including filtering, time-window limits, basic authentication and 404 responses for empty
+// flight queries. In addition, API credit budgets and faults such as latency, server errors,
+// truncated bodies and malformed state rows can be simulated, see Faults and Server.SetCredits.
+package openskytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	opensky "github.com/ororatech/go-opensky-api"
+)
+
+// Maximum time intervals accepted by the flight endpoints.
+const (
+	MaxFlightsInterval         = 2 * time.Hour       // Maximum interval of /flights/all.
+	MaxAircraftFlightsInterval = 30 * 24 * time.Hour // Maximum interval of /flights/aircraft.
+	MaxAirportFlightsInterval  = 7 * 24 * time.Hour  // Maximum interval of /flights/arrival and /flights/departure.
+)
+
+// A local OpenSky API server, serving in-memory fixtures.
+// Fixtures may be added at any time, also while requests are served.
+//
+// The API is served at the root of the server URL, e.g. /states/all. Use NewClient to create a
+// client for the server.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	users     map[string]string     // Passwords of the accounts by username.
+	states    []snapshot            // State vectors, ordered by time.
+	ownStates map[string][]snapshot // State vectors of own sensors by username, ordered by time.
+	flights   []opensky.Flight
+	tracks    []opensky.Track
+
+	faults      Faults                    // Simulated faults.
+	failures    int                       // Remaining requests of the current server error burst.
+	truncations int                       // Remaining responses to truncate.
+	credits     map[string]*creditAccount // API credit budgets by username, "" for anonymous requests.
+}
+
+// State vectors of a single point in time.
+type snapshot struct {
+	time   time.Time
+	states []opensky.State
+}
+
+// Option for configuring a Server.
+type Option func(s *Server)
+
+// Adds an account, which may authenticate via basic authentication.
+func WithUser(username string, password string) Option {
+	return func(s *Server) {
+		s.users[username] = password
+	}
+}
+
+// Creates and starts a new mock server without any fixtures.
+// The server must be closed after use.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		users:     map[string]string{},
+		ownStates: map[string][]snapshot{},
+		credits:   map[string]*creditAccount{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Creates a new client for the server. The credentials are optional, like for opensky.NewClient.
+func (s *Server) NewClient(username string, password string, opts ...opensky.Option) *opensky.Client {
+	return opensky.NewClient(username, password, append([]opensky.Option{opensky.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// Adds an account, which may authenticate via basic authentication.
+func (s *Server) AddUser(username string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// Adds the state vectors of a point in time, served by /states/all.
+// States added for an existing time are appended to the ones of that time.
+func (s *Server) AddStates(t time.Time, states ...opensky.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states = addSnapshot(s.states, t, states)
+}
+
+// Adds the state vectors of a point in time, served by /states/own for the passed user only.
+func (s *Server) AddOwnStates(username string, t time.Time, states ...opensky.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ownStates[username] = addSnapshot(s.ownStates[username], t, states)
+}
+
+// Adds flights, served by the /flights endpoints.
+func (s *Server) AddFlights(flights ...opensky.Flight) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flights = append(s.flights, flights...)
+}
+
+// Adds tracks, served by /tracks/all.
+func (s *Server) AddTracks(tracks ...opensky.Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tracks = append(s.tracks, tracks...)
+}
+
+// Inserts the states into the snapshot of the passed time, keeping the snapshots ordered by time.
+func addSnapshot(snapshots []snapshot, t time.Time, states []opensky.State) []snapshot {
+	i := sort.Search(len(snapshots), func(i int) bool {
+		return !snapshots[i].time.Before(t)
+	})
+	if i < len(snapshots) && snapshots[i].time.Equal(t) {
+		snapshots[i].states = append(snapshots[i].states, states...)
+		return snapshots
+	}
+	snapshots = append(snapshots, snapshot{})
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = snapshot{time: t, states: append([]opensky.State(nil), states...)}
+	return snapshots
+}
+
+// Error with the HTTP status code to respond with.
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+// Creates a 400 Bad Request error.
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	errUnauthorized = &httpError{status: http.StatusUnauthorized}
+	errNotFound     = &httpError{status: http.StatusNotFound}
+)
+
+// Dispatches a request to the handler of its endpoint, and writes the response.
+// Simulated faults are applied before and after handling the request.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	faults := s.faults
+	fail := s.failures > 0
+	if fail {
+		s.failures--
+	}
+	s.mu.Unlock()
+	if faults.Latency > 0 {
+		timer := time.NewTimer(faults.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fail {
+		w.WriteHeader(faults.ServerErrorStatus)
+		return
+	}
+	s.mu.Lock()
+	body, err := s.handle(r, w.Header())
+	truncate := err == nil && s.truncations > 0
+	if truncate {
+		s.truncations--
+	}
+	s.mu.Unlock()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if httpErr, ok := err.(*httpError); ok {
+			status = httpErr.status
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if truncate {
+		// The announced length exceeds the written body, so that the connection is closed early
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		body = body[:len(body)/2]
+	}
+	_, _ = w.Write(body)
+}
+
+// Returns the response body for a request, and sets additional response headers.
+// Must be called with the lock held.
+func (s *Server) handle(r *http.Request, header http.Header) ([]byte, error) {
+	username, authenticated, err := s.authenticate(r)
+	if err != nil {
+		return nil, err
+	}
+	q := r.URL.Query()
+	switch r.URL.Path {
+	case "/states/all":
+		body, err := s.handleStates(s.states, q)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.chargeCredits(username, q, header); err != nil {
+			return nil, err
+		}
+		return body, nil
+	case "/states/own":
+		if !authenticated {
+			return nil, errUnauthorized
+		}
+		return s.handleStates(s.ownStates[username], q)
+	case "/flights/all":
+		return s.handleFlights(q, false, MaxFlightsInterval, func(f opensky.Flight, begin, end int64) bool {
+			return overlaps(f, begin, end)
+		})
+	case "/flights/aircraft":
+		icao24 := strings.ToLower(q.Get("icao24"))
+		if icao24 == "" {
+			return nil, badRequest("icao24 is required")
+		}
+		return s.handleFlights(q, false, MaxAircraftFlightsInterval, func(f opensky.Flight, begin, end int64) bool {
+			return strings.ToLower(f.ICAO24) == icao24 && overlaps(f, begin, end)
+		})
+	case "/flights/arrival":
+		return s.handleFlights(q, true, MaxAirportFlightsInterval, func(f opensky.Flight, begin, end int64) bool {
+			return f.EstArrivalAirport == q.Get("airport") && within(f.LastSeen, begin, end)
+		})
+	case "/flights/departure":
+		return s.handleFlights(q, true, MaxAirportFlightsInterval, func(f opensky.Flight, begin, end int64) bool {
+			return f.EstDepartureAirport == q.Get("airport") && within(f.FirstSeen, begin, end)
+		})
+	case "/tracks/all":
+		return s.handleTracks(q)
+	}
+	return nil, errNotFound
+}
+
+// Checks the basic authentication credentials of the request, if there are any.
+// Requests with invalid credentials are rejected, requests without credentials are anonymous.
+func (s *Server) authenticate(r *http.Request) (username string, authenticated bool, err error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false, nil
+	}
+	if expected, exists := s.users[username]; !exists || expected != password {
+		return "", false, errUnauthorized
+	}
+	return username, true, nil
+}
+
+// Serves the latest snapshot at or before the requested time, filtered by the query parameters.
+func (s *Server) handleStates(snapshots []snapshot, q url.Values) ([]byte, error) {
+	var t int64
+	if raw := q.Get("time"); raw != "" {
+		var err error
+		if t, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, badRequest("invalid time %q", raw)
+		}
+	}
+	bbox, err := parseBoundingBox(q)
+	if err != nil {
+		return nil, err
+	}
+	icao24 := map[string]bool{}
+	for _, values := range q["icao24"] {
+		for _, v := range strings.Split(values, ",") {
+			icao24[strings.ToLower(v)] = true
+		}
+	}
+	serials := map[int]bool{}
+	for _, values := range q["serials"] {
+		for _, v := range strings.Split(values, ",") {
+			serial, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, badRequest("invalid serial %q", v)
+			}
+			serials[serial] = true
+		}
+	}
+	extended := q.Get("extended") == "1"
+	// Find snapshot
+	var response opensky.GetStatesResponse
+	response.Time = time.Unix(t, 0)
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if t == 0 || snapshots[i].time.Unix() <= t {
+			response.Time = snapshots[i].time
+			for _, state := range snapshots[i].states {
+				if matchesState(state, icao24, bbox, serials) {
+					if !extended {
+						state.Category = opensky.CategoryNoInformation
+					}
+					response.States = append(response.States, state)
+				}
+			}
+			break
+		}
+	}
+	body, err := opensky.MarshalStatesResponse(response)
+	if err != nil || s.faults.MalformedRows <= 0 {
+		return body, err
+	}
+	return addMalformedRows(body, s.faults.MalformedRows)
+}
+
+// Reports whether the state passes the filters of a state query.
+func matchesState(state opensky.State, icao24 map[string]bool, bbox *opensky.BoundingBox, serials map[int]bool) bool {
+	if len(icao24) > 0 && !icao24[strings.ToLower(state.ICAO24)] {
+		return false
+	}
+	if bbox != nil && (state.Latitude == nil || state.Longitude == nil || !bbox.Contains(*state.Latitude, *state.Longitude)) {
+		return false
+	}
+	if len(serials) > 0 {
+		for _, sensor := range state.Sensors {
+			if serials[sensor] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Parses the bounding box parameters, which must either be all present or all absent.
+func parseBoundingBox(q url.Values) (*opensky.BoundingBox, error) {
+	names := []string{"lamin", "lomin", "lamax", "lomax"}
+	var values [4]float64
+	present := 0
+	for i, name := range names {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, badRequest("invalid %s %q", name, raw)
+		}
+		values[i] = v
+		present++
+	}
+	switch present {
+	case 0:
+		return nil, nil
+	case len(names):
+		return &opensky.BoundingBox{LatMin: values[0], LonMin: values[1], LatMax: values[2], LonMax: values[3]}, nil
+	}
+	return nil, badRequest("incomplete bounding box")
+}
+
+// Serves the flights matching the passed function, after checking the common parameters.
+// Responds with 404, if no flights match.
+func (s *Server) handleFlights(q url.Values, airport bool, max time.Duration, match func(f opensky.Flight, begin int64, end int64) bool) ([]byte, error) {
+	begin, err := parseRequiredInt(q, "begin")
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseRequiredInt(q, "end")
+	if err != nil {
+		return nil, err
+	}
+	if end < begin {
+		return nil, badRequest("end must not be before begin")
+	}
+	if time.Duration(end-begin)*time.Second > max {
+		return nil, badRequest("the time interval must not be larger than %v", max)
+	}
+	if airport && q.Get("airport") == "" {
+		return nil, badRequest("airport is required")
+	}
+	var flights []opensky.Flight
+	for _, f := range s.flights {
+		if match(f, begin, end) {
+			flights = append(flights, f)
+		}
+	}
+	if len(flights) == 0 {
+		return nil, errNotFound
+	}
+	return json.Marshal(flights)
+}
+
+// Reports whether the flight was seen at any time within [begin, end].
+func overlaps(f opensky.Flight, begin int64, end int64) bool {
+	return f.FirstSeen.Unix() <= end && f.LastSeen.Unix() >= begin
+}
+
+// Reports whether the time is within [begin, end].
+func within(t opensky.UnixTime, begin int64, end int64) bool {
+	return t.Unix() >= begin && t.Unix() <= end
+}
+
+// Serves the track of an aircraft at the requested time. For time 0, the most recent track is served.
+func (s *Server) handleTracks(q url.Values) ([]byte, error) {
+	icao24 := strings.ToLower(q.Get("icao24"))
+	if icao24 == "" {
+		return nil, badRequest("icao24 is required")
+	}
+	var t int64
+	if raw := q.Get("time"); raw != "" {
+		var err error
+		if t, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, badRequest("invalid time %q", raw)
+		}
+	}
+	var found *opensky.Track
+	for i, track := range s.tracks {
+		if strings.ToLower(track.ICAO24) != icao24 {
+			continue
+		}
+		if t == 0 {
+			if found == nil || track.EndTime.After(found.EndTime.Time) {
+				found = &s.tracks[i]
+			}
+		} else if track.StartTime.Unix() <= t && t <= track.EndTime.Unix() {
+			found = &s.tracks[i]
+			break
+		}
+	}
+	if found == nil {
+		return nil, errNotFound
+	}
+	return marshalTrack(*found)
+}
+
+// Encodes a track in the format of /tracks/all, with waypoints as positional arrays.
+func marshalTrack(track opensky.Track) ([]byte, error) {
+	path := make([][]interface{}, len(track.Path))
+	for i, w := range track.Path {
+		path[i] = []interface{}{w.Time.Unix(), w.Latitude, w.Longitude, w.BarometricAltitude, w.Heading, w.OnGround}
+	}
+	var callSign *string
+	if track.CallSign != "" {
+		callSign = &track.CallSign
+	}
+	return json.Marshal(struct {
+		ICAO24    string          `json:"icao24"`
+		CallSign  *string         `json:"callsign"`
+		StartTime int64           `json:"startTime"`
+		EndTime   int64           `json:"endTime"`
+		Path      [][]interface{} `json:"path"`
+	}{track.ICAO24, callSign, track.StartTime.Unix(), track.EndTime.Unix(), path})
+}
+
+// Parses a required integer parameter.
+func parseRequiredInt(q url.Values, name string) (int64, error) {
+	raw := q.Get(name)
+	if raw == "" {
+		return 0, badRequest("%s is required", name)
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, badRequest("invalid %s %q", name, raw)
+	}
+	return v, nil
+}
@@ -0,0 +1,239 @@
+package openskytest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	opensky "github.com/ororatech/go-opensky-api"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFloat(f float64) *float64 {
+	return &f
+}
+
+func newState(icao24 string, lat float64, lon float64, lastContact int64) opensky.State {
+	return opensky.State{
+		ICAO24:        icao24,
+		OriginCountry: "Germany",
+		LastContact:   opensky.UnixTime{Time: time.Unix(lastContact, 0)},
+		Latitude:      newFloat(lat),
+		Longitude:     newFloat(lon),
+		Sensors:       []int{1000},
+		Category:      opensky.CategoryHeavy,
+	}
+}
+
+func icao24s(states []opensky.State) (icao24 []string) {
+	for _, state := range states {
+		icao24 = append(icao24, state.ICAO24)
+	}
+	return
+}
+
+func TestStates(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.AddStates(time.Unix(1000, 0), newState("3c6444", 50, 8, 1000), newState("a50c7c", 40, -100, 1000))
+	server.AddStates(time.Unix(1010, 0), newState("3c6444", 50.1, 8.1, 1010), newState("ae1fa7", 45, 170, 1010))
+	server.AddStates(time.Unix(1000, 0), newState("4b1805", 47, 8, 1000))
+	client := server.NewClient("", "")
+
+	type testCase struct {
+		time           time.Time
+		icao24         []string
+		bbox           *opensky.BoundingBox
+		expectedTime   time.Time
+		expectedICAO24 []string
+	}
+	cases := []testCase{
+		// Latest snapshot
+		{time.Time{}, nil, nil, time.Unix(1010, 0), []string{"3c6444", "ae1fa7"}},
+		// Latest snapshot at or before the requested time
+		{time.Unix(1005, 0), nil, nil, time.Unix(1000, 0), []string{"3c6444", "a50c7c", "4b1805"}},
+		{time.Unix(1010, 0), nil, nil, time.Unix(1010, 0), []string{"3c6444", "ae1fa7"}},
+		{time.Unix(999, 0), nil, nil, time.Unix(999, 0), nil},
+		// Filters
+		{time.Unix(1000, 0), []string{"A50C7C", "4b1805"}, nil, time.Unix(1000, 0), []string{"a50c7c", "4b1805"}},
+		{time.Unix(1000, 0), nil, &opensky.BoundingBox{LatMin: 45, LonMin: 5, LatMax: 55, LonMax: 10}, time.Unix(1000, 0), []string{"3c6444", "4b1805"}},
+		{time.Unix(1000, 0), []string{"3c6444"}, &opensky.BoundingBox{LatMin: 48, LonMin: 5, LatMax: 55, LonMax: 10}, time.Unix(1000, 0), []string{"3c6444"}},
+		// Bounding boxes crossing the antimeridian are split by the client
+		{time.Time{}, nil, &opensky.BoundingBox{LatMin: 40, LonMin: 160, LatMax: 50, LonMax: -160}, time.Unix(1010, 0), []string{"ae1fa7"}},
+	}
+	for _, c := range cases {
+		response, err := client.GetStates(c.time, c.icao24, c.bbox)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expectedTime, response.Time)
+		assert.Equal(t, c.expectedICAO24, icao24s(response.States))
+	}
+	// The category is only included in extended state vectors
+	response, err := client.GetStates(time.Time{}, []string{"3c6444"}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, response.States, 1) {
+		assert.Equal(t, opensky.CategoryNoInformation, response.States[0].Category)
+		assert.Equal(t, 50.1, *response.States[0].Latitude)
+	}
+	response, err = client.GetExtendedStates(time.Time{}, []string{"3c6444"}, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, response.States, 1) {
+		assert.Equal(t, opensky.CategoryHeavy, response.States[0].Category)
+	}
+}
+
+func TestOwnStates(t *testing.T) {
+	server := NewServer(WithUser("alice", "secret"))
+	defer server.Close()
+	server.AddUser("bob", "secret")
+	own := newState("3c6444", 50, 8, 1000)
+	other := newState("a50c7c", 50, 8, 1000)
+	other.Sensors = []int{1042}
+	server.AddOwnStates("alice", time.Unix(1000, 0), own, other)
+	// Anonymous requests and invalid credentials are rejected
+	_, err := server.NewClient("", "").GetOwnStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, opensky.ErrUnauthorized))
+	_, err = server.NewClient("alice", "wrong").GetOwnStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, opensky.ErrUnauthorized))
+	_, err = server.NewClient("alice", "wrong").GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, opensky.ErrUnauthorized))
+	// Own states of the user
+	client := server.NewClient("alice", "secret")
+	response, err := client.GetOwnStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3c6444", "a50c7c"}, icao24s(response.States))
+	response, err = client.GetOwnStates(time.Time{}, nil, []int{1042})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a50c7c"}, icao24s(response.States))
+	// Other users have no own states
+	response, err = server.NewClient("bob", "secret").GetOwnStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, response.States)
+}
+
+func TestFlights(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	flights := []opensky.Flight{
+		{
+			ICAO24:              "3c6444",
+			FirstSeen:           opensky.UnixTime{Time: time.Unix(10000, 0)},
+			EstDepartureAirport: "EDDF",
+			LastSeen:            opensky.UnixTime{Time: time.Unix(13000, 0)},
+			EstArrivalAirport:   "EGLL",
+			CallSign:            "DLH900  ",
+		},
+		{
+			ICAO24:              "a50c7c",
+			FirstSeen:           opensky.UnixTime{Time: time.Unix(12000, 0)},
+			EstDepartureAirport: "EGLL",
+			LastSeen:            opensky.UnixTime{Time: time.Unix(20000, 0)},
+		},
+	}
+	server.AddFlights(flights...)
+	client := server.NewClient("", "")
+	// All flights
+	result, err := client.GetFlights(time.Unix(11000, 0), time.Unix(12500, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, flights, result)
+	_, err = client.GetFlights(time.Unix(0, 0), time.Unix(5000, 0))
+	assert.True(t, errors.Is(err, opensky.ErrNotFound))
+	_, err = client.GetFlights(time.Unix(0, 0), time.Unix(7201, 0))
+	assert.Equal(t, http.StatusBadRequest, statusCode(err))
+	// Flights by aircraft
+	result, err = client.GetFlightsByAircraft("A50C7C", time.Unix(0, 0), time.Unix(15000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, flights[1:], result)
+	_, err = client.GetFlightsByAircraft("a50c7c", time.Unix(0, 0), time.Unix(31*24*3600, 0))
+	assert.Equal(t, http.StatusBadRequest, statusCode(err))
+	// Range queries are split into compliant windows
+	result, err = client.GetFlightsRange(time.Unix(0, 0), time.Unix(24*3600, 0), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, flights, result)
+	// Arrivals and departures
+	result, err = client.GetArrivalsByAirport("EGLL", time.Unix(0, 0), time.Unix(15000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, flights[:1], result)
+	result, err = client.GetDeparturesByAirport("egll", time.Unix(0, 0), time.Unix(15000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, flights[1:], result)
+	_, err = client.GetArrivalsByAirport("EDDF", time.Unix(0, 0), time.Unix(15000, 0))
+	assert.True(t, errors.Is(err, opensky.ErrNotFound))
+	// Empty results with WithEmptyFlightsOnNotFound
+	result, err = server.NewClient("", "", opensky.WithEmptyFlightsOnNotFound()).GetFlights(time.Unix(0, 0), time.Unix(5000, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestTracks(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	tracks := []opensky.Track{
+		{
+			ICAO24:    "3c6444",
+			CallSign:  "DLH900  ",
+			StartTime: opensky.UnixTime{Time: time.Unix(1000, 0)},
+			EndTime:   opensky.UnixTime{Time: time.Unix(2000, 0)},
+			Path: []opensky.Waypoint{
+				{Time: opensky.UnixTime{Time: time.Unix(1000, 0)}, Latitude: newFloat(50), Longitude: newFloat(8), OnGround: true},
+				{Time: opensky.UnixTime{Time: time.Unix(2000, 0)}, Latitude: newFloat(51), Longitude: newFloat(0), BarometricAltitude: newFloat(1000), Heading: newFloat(270)},
+			},
+		},
+		{
+			ICAO24:    "3c6444",
+			StartTime: opensky.UnixTime{Time: time.Unix(5000, 0)},
+			EndTime:   opensky.UnixTime{Time: time.Unix(6000, 0)},
+			Path:      []opensky.Waypoint{},
+		},
+	}
+	server.AddTracks(tracks...)
+	client := server.NewClient("", "")
+	track, err := client.GetTrack("3c6444", time.Unix(1500, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, tracks[0], track)
+	// Live track
+	track, err = client.GetTrack("3c6444", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(5000, 0), track.StartTime.Time)
+	_, err = client.GetTrack("3c6444", time.Unix(3000, 0))
+	assert.True(t, errors.Is(err, opensky.ErrNotFound))
+	_, err = client.GetTrack("a50c7c", time.Time{})
+	assert.True(t, errors.Is(err, opensky.ErrNotFound))
+}
+
+func TestBadRequests(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	cases := map[string]int{
+		"/states/all?lamin=45":                             http.StatusBadRequest,
+		"/states/all?time=now":                             http.StatusBadRequest,
+		"/flights/all?begin=0":                             http.StatusBadRequest,
+		"/flights/all?begin=100&end=0":                     http.StatusBadRequest,
+		"/flights/aircraft?begin=0&end=100":                http.StatusBadRequest,
+		"/flights/arrival?begin=0&end=100":                 http.StatusBadRequest,
+		"/flights/arrival?airport=EDDF&begin=0&end=700000": http.StatusBadRequest,
+		"/tracks/all":                                      http.StatusBadRequest,
+		"/unknown":                                         http.StatusNotFound,
+	}
+	for path, expectedStatus := range cases {
+		resp, err := http.Get(server.URL + path)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, expectedStatus, resp.StatusCode, path)
+		}
+	}
+	resp, err := http.Post(server.URL+"/states/all", "application/json", nil)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+// Returns the status code of an *opensky.APIError, or 0 for other errors.
+func statusCode(err error) int {
+	var apiErr *opensky.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package opensky
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option customizes a Client on creation.
+// Options are applied in the order they are passed to NewClient.
+type Option func(c *Client)
+
+// Sets the base URL of the OpenSky API, e.g. to point the client at a caching proxy or a local mock.
+// Any trailing slash is removed. Defaults to https://opensky-network.org/api.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// Sets the HTTP client used for all requests, e.g. to provide a custom transport.
+// The timeout of the passed client replaces the default 5 minute timeout, unless WithTimeout is
+// passed as well.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// Sets the timeout for every request.
+// It takes precedence over the timeout of a client passed with WithHTTPClient, regardless of the
+// order of the options. The passed HTTP client is copied, not modified.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = &timeout
+	}
+}
+
+// Sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// Sets the authenticator used for every request, replacing the basic authentication derived from
+// the username and password passed to NewClient.
+// Passing nil disables authentication.
+func WithAuthenticator(auth Authenticator) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// Makes flight queries return an empty slice instead of an error, when OpenSky responds with 404
+// because no flights were found for the given time period.
+func WithEmptyFlightsOnNotFound() Option {
+	return func(c *Client) {
+		c.emptyFlightsOnNotFound = true
+	}
+}
+
+// Sets the retry policy for every request.
+// By default, requests are not retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		p := policy.withDefaults()
+		c.retryPolicy = &p
+	}
+}
+
+// Sets a daily credit budget, which is charged for every GetStates query according to
+// StatesCreditCost. Queries for own states are free and not charged.
+// Credits of failed queries are returned to the budget.
+func WithCreditBudget(budget *CreditBudget) Option {
+	return func(c *Client) {
+		c.creditBudget = budget
+	}
+}
+
+// Makes state queries skip invalid state arrays instead of failing entirely.
+// The skipped state arrays are reported in the Errors field of the response.
+func WithLenientParsing() Option {
+	return func(c *Client) {
+		c.lenientParsing = true
+	}
+}
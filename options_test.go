@@ -0,0 +1,86 @@
+package opensky
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingTransport struct {
+	count int
+}
+
+func (t *countingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.count++
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	client := NewClient("", "")
+	assert.Equal(t, baseOpenSkyURL, client.baseURL)
+	assert.Equal(t, "", client.userAgent)
+	assert.Equal(t, 5*time.Minute, client.httpClient.Timeout)
+}
+
+func TestClientOptions(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+	}))
+	defer server.Close()
+	transport := &countingTransport{}
+	httpClient := &http.Client{Transport: transport, Timeout: time.Minute}
+	client := NewClient("user", "pass",
+		WithBaseURL(server.URL+"/"),
+		WithHTTPClient(httpClient),
+		WithTimeout(10*time.Second),
+		WithUserAgent("opensky-test/1.0"),
+	)
+	assert.Equal(t, server.URL, client.baseURL)
+	assert.Equal(t, 10*time.Second, client.httpClient.Timeout)
+	assert.Equal(t, transport, client.httpClient.Transport)
+	// The passed HTTP client must not be modified
+	assert.Equal(t, time.Minute, httpClient.Timeout)
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, transport.count)
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "/states/all", requests[0].URL.Path)
+		assert.Equal(t, "opensky-test/1.0", requests[0].Header.Get("User-Agent"))
+		username, password, ok := requests[0].BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", username)
+		assert.Equal(t, "pass", password)
+	}
+}
+
+func TestWithTimeoutOrder(t *testing.T) {
+	transport := &countingTransport{}
+	httpClient := &http.Client{Transport: transport, Timeout: time.Minute}
+	type testCase struct {
+		opts []Option
+	}
+	cases := []testCase{
+		{[]Option{WithHTTPClient(httpClient), WithTimeout(10 * time.Second)}},
+		{[]Option{WithTimeout(10 * time.Second), WithHTTPClient(httpClient)}},
+	}
+	for i, c := range cases {
+		client := NewClient("", "", c.opts...)
+		assert.Equal(t, 10*time.Second, client.httpClient.Timeout, i)
+		assert.Equal(t, transport, client.httpClient.Transport, i)
+	}
+	// The passed HTTP client must not be modified
+	assert.Equal(t, time.Minute, httpClient.Timeout)
+	// Without a custom HTTP client, the default one is used
+	client := NewClient("", "", WithTimeout(0))
+	assert.Equal(t, time.Duration(0), client.httpClient.Timeout)
+}
+
+func TestWithHTTPClientNil(t *testing.T) {
+	client := NewClient("", "", WithHTTPClient(nil))
+	assert.NotNil(t, client.httpClient)
+}
@@ -0,0 +1,171 @@
+package opensky
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Query for state vectors, executed by Client.QueryStates.
+// The zero value queries the current state vectors of all aircraft.
+type StatesQuery struct {
+	Time        time.Time    // Time of the state vectors. The current time is used, if zero.
+	ICAO24      []string     // ICAO24 addresses of the aircraft to filter for. All aircraft are returned, if empty.
+	BoundingBox *BoundingBox // Area to filter for. The whole world is queried, if nil. Not supported for own states.
+	Serials     []int        // Serial numbers of own receivers to filter for. Only supported for own states.
+	Extended    bool         // Whether to request extended state vectors, which contain the aircraft category.
+	Own         bool         // Whether to query the state vectors of your own sensors (/states/own) instead of all states (/states/all).
+}
+
+// Returns the API credits OpenSky charges for the query. Own states are free of charge.
+func (q StatesQuery) creditCost() int {
+	if q.Own {
+		return 0
+	}
+	return StatesCreditCost(q.BoundingBox)
+}
+
+// Checks the parameters of the query, and returns a copy with normalized ICAO24 addresses.
+func (q StatesQuery) validate() (StatesQuery, error) {
+	var v validator
+	q.ICAO24 = v.icao24List(q.ICAO24)
+	v.boundingBox(q.BoundingBox)
+	if q.Own && q.BoundingBox != nil {
+		v.addf("bbox", "not supported for own states")
+	}
+	if !q.Own && len(q.Serials) > 0 {
+		v.addf("serials", "only supported for own states")
+	}
+	return q, v.err()
+}
+
+// Retrieves state vectors according to the passed query.
+//
+// GetStates, GetExtendedStates and GetOwnStates are shortcuts for common queries.
+// The passed context controls cancellation and deadline of the request.
+//
+// If the bounding box of the query crosses the antimeridian, two requests are sent, one for each
+// side. Their states are merged, keeping the state with the most recent LastContact for aircraft
+// reported by both. Note that StreamStates cannot do this and keeps the first state received
+// instead.
+func (c *Client) QueryStates(ctx context.Context, query StatesQuery) (response GetStatesResponse, err error) {
+	if query.BoundingBox == nil || !query.BoundingBox.CrossesAntimeridian() {
+		return c.queryStates(ctx, query)
+	}
+	if _, err = query.validate(); err != nil {
+		return
+	}
+	var responses []GetStatesResponse
+	for _, bbox := range query.BoundingBox.Split() {
+		bbox := bbox
+		part := query
+		part.BoundingBox = &bbox
+		var partResponse GetStatesResponse
+		partResponse, err = c.queryStates(ctx, part)
+		setErrorsBoundingBox(partResponse, bbox)
+		responses = append(responses, partResponse)
+		if err != nil {
+			break
+		}
+	}
+	return mergeStatesResponses(responses), err
+}
+
+// Executes a query with a single request.
+func (c *Client) queryStates(ctx context.Context, query StatesQuery) (response GetStatesResponse, err error) {
+	request, err := c.newStatesRequest(ctx, query)
+	if err != nil {
+		return
+	}
+	// Charge credits
+	cost := query.creditCost()
+	if err = c.reserveCredits(cost); err != nil {
+		return
+	}
+	// Fetch response
+	var rawResponse unstructuredStateResponse
+	err = c.doHTTP(request, &rawResponse)
+	if err != nil {
+		c.refundCredits(cost)
+		return
+	}
+	return parseStatesResponse(rawResponse, c.lenientParsing)
+}
+
+// Creates a new request for the /states/all or /states/own endpoint, with all optional parameters
+// of the query set.
+func (c *Client) newStatesRequest(ctx context.Context, query StatesQuery) (request *http.Request, err error) {
+	if query, err = query.validate(); err != nil {
+		return
+	}
+	endpoint := "all"
+	if query.Own {
+		endpoint = "own"
+	}
+	request, err = c.newRequest(ctx, "GET", fmt.Sprintf("%s/states/%s", c.baseURL, endpoint))
+	if err != nil {
+		return
+	}
+	q := request.URL.Query()
+	// Add optional parameters
+	if !query.Time.IsZero() {
+		q.Set("time", fmt.Sprintf("%v", query.Time.Unix()))
+	}
+	if len(query.ICAO24) > 0 {
+		q.Set("icao24", strings.Join(query.ICAO24, ","))
+	}
+	if bbox := query.BoundingBox; bbox != nil {
+		q.Set("lamin", fmt.Sprintf("%v", bbox.LatMin))
+		q.Set("lomin", fmt.Sprintf("%v", bbox.LonMin))
+		q.Set("lamax", fmt.Sprintf("%v", bbox.LatMax))
+		q.Set("lomax", fmt.Sprintf("%v", bbox.LonMax))
+	}
+	if len(query.Serials) > 0 {
+		serials := make([]string, len(query.Serials))
+		for i, s := range query.Serials {
+			serials[i] = fmt.Sprintf("%v", s)
+		}
+		q.Set("serials", strings.Join(serials, ","))
+	}
+	if query.Extended {
+		q.Set("extended", "1")
+	}
+	request.URL.RawQuery = q.Encode()
+	return
+}
+
+// Records the area of the request on the parse errors of its response, before it is merged with
+// the responses of other areas.
+func setErrorsBoundingBox(response GetStatesResponse, bbox BoundingBox) {
+	for _, parseErr := range response.Errors {
+		parseErr.BoundingBox = &bbox
+	}
+}
+
+// Merges the states of several responses, e.g. of adjacent areas.
+//
+// Aircraft contained in more than one response are only kept once, with the state having the most
+// recent LastContact. The time of the merged response is the most recent one of all responses.
+func mergeStatesResponses(responses []GetStatesResponse) (merged GetStatesResponse) {
+	indexes := map[string]int{}
+	for i, response := range responses {
+		if i == 0 || response.Time.After(merged.Time) {
+			merged.Time = response.Time
+		}
+		merged.Errors = append(merged.Errors, response.Errors...)
+		for _, state := range response.States {
+			index, ok := indexes[state.ICAO24]
+			if !ok {
+				indexes[state.ICAO24] = len(merged.States)
+				merged.States = append(merged.States, state)
+				continue
+			}
+			if state.LastContact.After(merged.States[index].LastContact.Time) {
+				merged.States[index] = state
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,178 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryStates(t *testing.T) {
+	type testCase struct {
+		query         StatesQuery
+		expectedPath  string
+		expectedQuery string
+		expectedCost  int
+	}
+	bbox := &BoundingBox{LatMin: 45.8389, LonMin: 5.9962, LatMax: 47.8229, LonMax: 10.5226}
+	cases := []testCase{
+		{StatesQuery{}, "/states/all", "", 4},
+		{StatesQuery{Time: time.Unix(1624958210, 0)}, "/states/all", "time=1624958210", 4},
+		{StatesQuery{ICAO24: []string{"AE1FA7", "a50c7c"}}, "/states/all", "icao24=ae1fa7%2Ca50c7c", 4},
+		{StatesQuery{BoundingBox: bbox}, "/states/all", "lamax=47.8229&lamin=45.8389&lomax=10.5226&lomin=5.9962", 1},
+		{StatesQuery{Extended: true}, "/states/all", "extended=1", 4},
+		{StatesQuery{Own: true}, "/states/own", "", 0},
+		{StatesQuery{Own: true, Time: time.Unix(1624958210, 0), ICAO24: []string{"ae1fa7"}, Serials: []int{1000, 1042}, Extended: true}, "/states/own", "extended=1&icao24=ae1fa7&serials=1000%2C1042&time=1624958210", 0},
+	}
+	for _, c := range cases {
+		var requests []*http.Request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			_, _ = w.Write([]byte(`{"time":1624958210,"states":[["a50c7c",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`))
+		}))
+		budget := NewCreditBudget(100, nil)
+		client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+		response, err := client.QueryStates(context.Background(), c.query)
+		server.Close()
+		assert.NoError(t, err)
+		assert.Len(t, response.States, 1)
+		assert.Equal(t, c.expectedCost, budget.Used())
+		if assert.Len(t, requests, 1) {
+			assert.Equal(t, c.expectedPath, requests[0].URL.Path)
+			assert.Equal(t, c.expectedQuery, requests[0].URL.RawQuery)
+		}
+	}
+}
+
+func TestQueryStatesValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %v", r.URL)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	cases := []StatesQuery{
+		{Own: true, BoundingBox: &BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}},
+		{Serials: []int{1000}},
+		{ICAO24: []string{"xyz"}},
+	}
+	for _, query := range cases {
+		_, err := client.QueryStates(context.Background(), query)
+		var validationErr *ValidationError
+		assert.True(t, errors.As(err, &validationErr), "%+v", query)
+	}
+}
+
+func TestQueryStatesAntimeridian(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Query().Get("lomin") == "170" {
+			_, _ = w.Write([]byte(`{"time":1624958210,"states":[["a50c7c",null,"United States",null,1624891429,179.9,0,null,false,null,null,null,null,null,null,false,0],["ae1fa7",null,"United States",null,1624891400,180,0,null,false,null,null,null,null,null,null,false,0]]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"time":1624958220,"states":[["ae1fa7",null,"United States",null,1624891430,-180,0,null,false,null,null,null,null,null,null,false,0],["3c6444",null,"Germany",null,1624891429,-175,0,null,false,null,null,null,null,null,null,false,0]]}`))
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(100, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	bbox := &BoundingBox{LatMin: -5, LonMin: 170, LatMax: 5, LonMax: -170}
+	response, err := client.QueryStates(context.Background(), StatesQuery{BoundingBox: bbox})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1624958220, 0), response.Time)
+	if assert.Len(t, response.States, 3) {
+		assert.Equal(t, "a50c7c", response.States[0].ICAO24)
+		// The freshest state of an aircraft reported on both sides is kept
+		assert.Equal(t, "ae1fa7", response.States[1].ICAO24)
+		assert.Equal(t, newUnixTime(1624891430), response.States[1].LastContact)
+		assert.Equal(t, "3c6444", response.States[2].ICAO24)
+	}
+	assert.Equal(t, StatesCreditCost(bbox), budget.Used())
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "lamax=5&lamin=-5&lomax=180&lomin=170", requests[0].URL.RawQuery)
+		assert.Equal(t, "lamax=5&lamin=-5&lomax=-170&lomin=-180", requests[1].URL.RawQuery)
+	}
+	// GetStates splits the box as well
+	requests = nil
+	_, err = client.GetStates(time.Time{}, nil, bbox)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 2)
+}
+
+func TestQueryStatesAntimeridianError(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	_, err := client.QueryStates(context.Background(), StatesQuery{BoundingBox: &BoundingBox{LatMin: -5, LonMin: 170, LatMax: 5, LonMax: -170}})
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, 1, requests)
+}
+
+func TestQueryStatesAntimeridianLenient(t *testing.T) {
+	// Both sides report an invalid state at the same position
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[["a50c7c",null,"United States",null,1624891429,null,null,null,"x",null,null,null,null,null,null,false,0],["` + r.URL.Query().Get("lomin") + `",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]]}`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL), WithLenientParsing())
+	bbox := &BoundingBox{LatMin: -5, LonMin: 170, LatMax: 5, LonMax: -170}
+	expectedAreas := []BoundingBox{
+		{LatMin: -5, LonMin: 170, LatMax: 5, LonMax: 180},
+		{LatMin: -5, LonMin: -180, LatMax: 5, LonMax: -170},
+	}
+	assertErrors := func(name string, parseErrors []*StateParseError) {
+		if !assert.Len(t, parseErrors, 2, name) {
+			return
+		}
+		for i, parseErr := range parseErrors {
+			assert.Equal(t, 0, parseErr.Index, name)
+			assert.Equal(t, "on_ground", parseErr.Field, name)
+			if assert.NotNil(t, parseErr.BoundingBox, name) {
+				assert.Equal(t, expectedAreas[i], *parseErr.BoundingBox, name)
+			}
+		}
+		assert.Equal(t, `invalid on_ground value at position 0 of area {LatMin:-5 LonMin:-180 LatMax:5 LonMax:-170}: x`, parseErrors[1].Error(), name)
+	}
+	response, err := client.QueryStates(context.Background(), StatesQuery{BoundingBox: bbox})
+	assert.NoError(t, err)
+	assert.Len(t, response.States, 2)
+	assertErrors("QueryStates", response.Errors)
+	states := 0
+	response, err = client.StreamStates(context.Background(), StatesQuery{BoundingBox: bbox}, func(state State) error {
+		states++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, states)
+	assertErrors("StreamStates", response.Errors)
+	// Errors of a single request have no area
+	response, err = client.QueryStates(context.Background(), StatesQuery{BoundingBox: &expectedAreas[0]})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Errors, 1) {
+		assert.Nil(t, response.Errors[0].BoundingBox)
+	}
+}
+
+func TestMergeStatesResponses(t *testing.T) {
+	state := func(icao24 string, lastContact int64) State {
+		return State{ICAO24: icao24, LastContact: newUnixTime(lastContact)}
+	}
+	parseErr := &StateParseError{Index: 1, Field: "icao24"}
+	responses := []GetStatesResponse{
+		{Time: time.Unix(20, 0), States: []State{state("a", 10), state("b", 10)}},
+		{Time: time.Unix(30, 0), States: []State{state("b", 5), state("c", 10)}, Errors: []*StateParseError{parseErr}},
+		{Time: time.Unix(10, 0), States: []State{state("a", 15)}},
+	}
+	merged := mergeStatesResponses(responses)
+	assert.Equal(t, time.Unix(30, 0), merged.Time)
+	assert.Equal(t, []State{state("a", 15), state("b", 10), state("c", 10)}, merged.States)
+	assert.Equal(t, []*StateParseError{parseErr}, merged.Errors)
+	assert.Equal(t, GetStatesResponse{}, mergeStatesResponses(nil))
+}
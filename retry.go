@@ -0,0 +1,154 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = time.Minute
+	defaultMultiplier     = 2.0
+)
+
+// Describes a single attempt of a request performed with a RetryPolicy.
+type RetryAttempt struct {
+	Attempt  int           // Number of the attempt, starting at 1.
+	Endpoint string        // URL path of the request, e.g. /api/states/all.
+	Err      error         // Error of the attempt. Nil if the attempt succeeded.
+	Wait     time.Duration // Delay before the next attempt. Zero if no further attempt follows.
+}
+
+// Policy for retrying failed requests with exponential backoff.
+//
+// Only idempotent requests (GET and HEAD) are retried, and only on network errors or on responses
+// with status code 429, 502, 503 or 504. If a response carries a Retry-After header, the next
+// attempt is delayed accordingly; if the requested delay exceeds MaxBackoff, the request is not
+// retried at all, since waiting within a single call would not be sensible (e.g. when the daily
+// API credits are exhausted).
+//
+// Zero values are replaced by sensible defaults.
+type RetryPolicy struct {
+	MaxAttempts    int           // Maximum number of attempts, including the first one. Values below 2 disable retries.
+	InitialBackoff time.Duration // Delay before the second attempt. Defaults to 1 second.
+	MaxBackoff     time.Duration // Upper bound for any delay between attempts. Defaults to 1 minute.
+	Multiplier     float64       // Factor by which the delay grows after each attempt. Defaults to 2.
+	Jitter         float64       // Fraction in [0, 1] by which each delay is randomly reduced. Defaults to 0 (no jitter).
+
+	OnAttempt func(attempt RetryAttempt)                       // Optional hook, invoked after every attempt.
+	Sleep     func(ctx context.Context, d time.Duration) error // Waits between attempts. Defaults to a timer, which is interrupted when ctx is done.
+	Random    func() float64                                   // Returns a random number in [0, 1) for the jitter. Defaults to math/rand.
+}
+
+// Returns a copy of the policy, with all zero values replaced by defaults.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaultMultiplier
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	} else if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	if p.Sleep == nil {
+		p.Sleep = sleepContext
+	}
+	if p.Random == nil {
+		p.Random = rand.Float64
+	}
+	return p
+}
+
+// Performs the request via the passed function, retrying as long as the policy allows.
+func (p *RetryPolicy) do(request *http.Request, doOnce func(*http.Request) (*http.Response, error)) (resp *http.Response, err error) {
+	ctx := request.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err = doOnce(request)
+		wait, retry := p.next(ctx, attempt, err)
+		if p.OnAttempt != nil {
+			p.OnAttempt(RetryAttempt{
+				Attempt:  attempt,
+				Endpoint: request.URL.Path,
+				Err:      err,
+				Wait:     wait,
+			})
+		}
+		if !retry {
+			return
+		}
+		if sleepErr := p.Sleep(ctx, wait); sleepErr != nil {
+			return nil, sleepErr
+		}
+	}
+}
+
+// Decides whether another attempt should follow the given one, and how long to wait before it.
+func (p *RetryPolicy) next(ctx context.Context, attempt int, err error) (wait time.Duration, retry bool) {
+	if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !isRetryable(err) {
+		return 0, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RateLimit.RetryAfter > 0 {
+		if apiErr.RateLimit.RetryAfter > p.MaxBackoff {
+			return 0, false
+		}
+		return apiErr.RateLimit.RetryAfter, true
+	}
+	return p.backoff(attempt), true
+}
+
+// Computes the delay after the given attempt, including jitter.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	backoff -= backoff * p.Jitter * p.Random()
+	return time.Duration(backoff)
+}
+
+// Reports whether a request failing with the passed error may be retried.
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Only network errors are retried, but not e.g. rejected credentials
+		var urlErr *url.Error
+		if !errors.As(err, &urlErr) {
+			return false
+		}
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Reports whether requests with the passed method may safely be sent more than once.
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// Waits for the passed duration, or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
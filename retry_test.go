@@ -0,0 +1,226 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a server, which responds with the passed status codes in order, followed by 200.
+func newSequenceServer(statusCodes []int, header http.Header) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(statusCodes) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statusCodes[requests-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+	}))
+	return server, &requests
+}
+
+// Returns a retry policy with a fake clock, which records every requested delay.
+func newTestRetryPolicy(maxAttempts int, waits *[]time.Duration, attempts *[]RetryAttempt) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    maxAttempts,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		OnAttempt: func(attempt RetryAttempt) {
+			*attempts = append(*attempts, attempt)
+		},
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			*waits = append(*waits, d)
+			return ctx.Err()
+		},
+		Random: func() float64 {
+			return 0.5
+		},
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	server, requests := newSequenceServer([]int{503, 502, 504, 429, 503}, nil)
+	defer server.Close()
+	var waits []time.Duration
+	var attempts []RetryAttempt
+	client := NewClient("", "", WithBaseURL(server.URL), WithRetryPolicy(newTestRetryPolicy(10, &waits, &attempts)))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, *requests)
+	// Exponential backoff, capped at 5s, reduced by 25% jitter
+	assert.Equal(t, []time.Duration{
+		750 * time.Millisecond,
+		1500 * time.Millisecond,
+		3 * time.Second,
+		3750 * time.Millisecond,
+		3750 * time.Millisecond,
+	}, waits)
+	if assert.Len(t, attempts, 6) {
+		for i, attempt := range attempts[:5] {
+			assert.Equal(t, i+1, attempt.Attempt)
+			assert.Equal(t, "/states/all", attempt.Endpoint)
+			assert.Error(t, attempt.Err)
+			assert.Equal(t, waits[i], attempt.Wait)
+		}
+		assert.Equal(t, RetryAttempt{Attempt: 6, Endpoint: "/states/all"}, attempts[5])
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	server, requests := newSequenceServer([]int{503, 503, 503, 503}, nil)
+	defer server.Close()
+	var waits []time.Duration
+	var attempts []RetryAttempt
+	client := NewClient("", "", WithBaseURL(server.URL), WithRetryPolicy(newTestRetryPolicy(3, &waits, &attempts)))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, 3, *requests)
+	assert.Len(t, waits, 2)
+	if assert.Len(t, attempts, 3) {
+		assert.Equal(t, time.Duration(0), attempts[2].Wait)
+	}
+}
+
+func TestRetryNonRetryableStatus(t *testing.T) {
+	for _, statusCode := range []int{400, 401, 403, 404, 500} {
+		server, requests := newSequenceServer([]int{statusCode}, nil)
+		var waits []time.Duration
+		var attempts []RetryAttempt
+		client := NewClient("", "", WithBaseURL(server.URL), WithRetryPolicy(newTestRetryPolicy(5, &waits, &attempts)))
+		_, err := client.GetStates(time.Time{}, nil, nil)
+		assert.Error(t, err)
+		assert.Equal(t, 1, *requests, "status %d", statusCode)
+		assert.Empty(t, waits)
+		server.Close()
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	// Retry-After within the max backoff is honored
+	server, requests := newSequenceServer([]int{429}, http.Header{"X-Rate-Limit-Retry-After-Seconds": {"4"}})
+	defer server.Close()
+	var waits []time.Duration
+	var attempts []RetryAttempt
+	client := NewClient("", "", WithBaseURL(server.URL), WithRetryPolicy(newTestRetryPolicy(5, &waits, &attempts)))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *requests)
+	assert.Equal(t, []time.Duration{4 * time.Second}, waits)
+	// Retry-After beyond the max backoff aborts immediately
+	server2, requests2 := newSequenceServer([]int{429}, http.Header{"Retry-After": {"3600"}})
+	defer server2.Close()
+	waits = nil
+	client = NewClient("", "", WithBaseURL(server2.URL), WithRetryPolicy(newTestRetryPolicy(5, &waits, &attempts)))
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, 1, *requests2)
+	assert.Empty(t, waits)
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	server, _ := newSequenceServer(nil, nil)
+	url := server.URL
+	server.Close()
+	var waits []time.Duration
+	var attempts []RetryAttempt
+	client := NewClient("", "", WithBaseURL(url), WithRetryPolicy(newTestRetryPolicy(3, &waits, &attempts)))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.Error(t, err)
+	assert.Len(t, attempts, 3)
+	assert.Len(t, waits, 2)
+}
+
+func TestRetryRefreshesCredentials(t *testing.T) {
+	var fetches int32
+	tokenServer := newTokenServer(t, 300, &fetches)
+	defer tokenServer.Close()
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		if len(authorization) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+	}))
+	defer server.Close()
+	now := time.Unix(1624891429, 0)
+	auth := NewClientCredentialsAuthenticator("my-client", "my-secret", tokenServer.URL, nil)
+	auth.now = func() time.Time { return now }
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			// The token expires while waiting for the next attempt
+			now = now.Add(10 * time.Minute)
+			return nil
+		},
+	}
+	client := NewClient("", "", WithBaseURL(server.URL), WithAuthenticator(auth), WithRetryPolicy(policy))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2", "Bearer token-3"}, authorization)
+	// Rejected credentials are not retried
+	var attempts []RetryAttempt
+	policy.OnAttempt = func(attempt RetryAttempt) {
+		attempts = append(attempts, attempt)
+	}
+	failing := NewClientCredentialsAuthenticator("my-client", "wrong-secret", tokenServer.URL, nil)
+	client = NewClient("", "", WithBaseURL(server.URL), WithAuthenticator(failing), WithRetryPolicy(policy))
+	_, err = client.GetStates(time.Time{}, nil, nil)
+	assert.Error(t, err)
+	assert.Len(t, attempts, 1)
+	assert.Len(t, authorization, 3)
+}
+
+func TestRetryContextCancelled(t *testing.T) {
+	server, requests := newSequenceServer([]int{503, 503, 503}, nil)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			// Cancel while waiting for the next attempt
+			cancel()
+			return sleepContext(ctx, d)
+		},
+	}
+	client := NewClient("", "", WithBaseURL(server.URL), WithRetryPolicy(policy))
+	_, err := client.GetStatesContext(ctx, time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, *requests)
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	p := RetryPolicy{Jitter: 2}.withDefaults()
+	assert.Equal(t, time.Second, p.InitialBackoff)
+	assert.Equal(t, time.Minute, p.MaxBackoff)
+	assert.Equal(t, 2.0, p.Multiplier)
+	assert.Equal(t, 1.0, p.Jitter)
+	assert.NotNil(t, p.Sleep)
+	assert.NotNil(t, p.Random)
+	// Zero value disables retries
+	server, requests := newSequenceServer([]int{503}, nil)
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL), WithRetryPolicy(RetryPolicy{}))
+	_, err := client.GetStates(time.Time{}, nil, nil)
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, 1, *requests)
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.NoError(t, sleepContext(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, errors.Is(sleepContext(ctx, time.Hour), context.Canceled))
+}
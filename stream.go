@@ -0,0 +1,165 @@
+package opensky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Retrieves state vectors according to the passed query like QueryStates, but decodes the
+// response while it is being received, and passes every state to fn as soon as it is parsed.
+// In contrast to QueryStates, the states are never held in memory at once, which makes this method
+// suitable for large responses, such as global snapshots. Extended and own states can be streamed
+// as well.
+//
+// The returned response contains the time of the state vectors and, in lenient mode, the parse
+// errors of skipped states, but no states. If fn returns an error, decoding is aborted and the
+// error is returned.
+//
+// If the bounding box crosses the antimeridian, both sides are streamed one after the other.
+// Aircraft reported on both sides are only passed to fn once, with the first state received, since
+// a state cannot be taken back once it was passed to fn. This differs from QueryStates and
+// GetStatesTiled, which keep the state with the most recent LastContact, so the two methods may
+// return different states for the same query.
+func (c *Client) StreamStates(ctx context.Context, query StatesQuery, fn func(state State) error) (response GetStatesResponse, err error) {
+	if query.BoundingBox == nil || !query.BoundingBox.CrossesAntimeridian() {
+		return c.streamStates(ctx, query, fn)
+	}
+	if _, err = query.validate(); err != nil {
+		return
+	}
+	seen := map[string]bool{}
+	var responses []GetStatesResponse
+	for _, part := range query.BoundingBox.Split() {
+		part := part
+		query.BoundingBox = &part
+		var partResponse GetStatesResponse
+		partResponse, err = c.streamStates(ctx, query, func(state State) error {
+			if seen[state.ICAO24] {
+				return nil
+			}
+			seen[state.ICAO24] = true
+			return fn(state)
+		})
+		setErrorsBoundingBox(partResponse, part)
+		responses = append(responses, partResponse)
+		if err != nil {
+			break
+		}
+	}
+	return mergeStatesResponses(responses), err
+}
+
+// Streams the states of a query with a single request.
+func (c *Client) streamStates(ctx context.Context, query StatesQuery, fn func(state State) error) (response GetStatesResponse, err error) {
+	request, err := c.newStatesRequest(ctx, query)
+	if err != nil {
+		return
+	}
+	// Charge credits
+	cost := query.creditCost()
+	if err = c.reserveCredits(cost); err != nil {
+		return
+	}
+	// Fetch response
+	resp, err := c.do(request)
+	if err != nil {
+		c.refundCredits(cost)
+		return
+	}
+	defer resp.Body.Close()
+	return decodeStatesStream(resp.Body, c.lenientParsing, fn)
+}
+
+// Decodes a state vectors response token by token, invoking fn for every parsed state.
+//
+// In lenient mode, invalid state arrays are skipped and collected in the Errors field of the
+// response. Otherwise, the first invalid state array aborts decoding with an error.
+func decodeStatesStream(r io.Reader, lenient bool, fn func(state State) error) (response GetStatesResponse, err error) {
+	response.Time = time.Unix(0, 0)
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err = expectDelim(decoder, '{'); err != nil {
+		return
+	}
+	for decoder.More() {
+		var token json.Token
+		token, err = decoder.Token()
+		if err != nil {
+			return
+		}
+		// Keys are matched case-insensitively, like encoding/json and DecodeStatesResponse do
+		key, _ := token.(string)
+		switch {
+		case strings.EqualFold(key, "time"):
+			var rawTime *int64
+			if err = decoder.Decode(&rawTime); err != nil {
+				return
+			}
+			if rawTime != nil {
+				response.Time = time.Unix(*rawTime, 0)
+			}
+		case strings.EqualFold(key, "states"):
+			if err = decodeStatesArray(decoder, lenient, &response, fn); err != nil {
+				return
+			}
+		default:
+			// Skip unknown fields
+			var raw json.RawMessage
+			if err = decoder.Decode(&raw); err != nil {
+				return
+			}
+		}
+	}
+	err = expectDelim(decoder, '}')
+	return
+}
+
+// Decodes the states array of a state vectors response, which may also be null.
+func decodeStatesArray(decoder *json.Decoder, lenient bool, response *GetStatesResponse, fn func(state State) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("invalid states value: unexpected %v", token)
+	}
+	for i := 0; decoder.More(); i++ {
+		var raw []interface{}
+		if err = decoder.Decode(&raw); err != nil {
+			return err
+		}
+		state, err := parseState(raw, i)
+		if err != nil {
+			var parseErr *StateParseError
+			if lenient && errors.As(err, &parseErr) {
+				response.Errors = append(response.Errors, parseErr)
+				continue
+			}
+			return err
+		}
+		if err = fn(state); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, ']')
+}
+
+// Reads the next token and checks that it is the passed delimiter.
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("invalid states response: expected %v, got %v", delim, token)
+	}
+	return nil
+}
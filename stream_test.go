@@ -0,0 +1,238 @@
+package opensky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Generates a state vectors response payload with n states, similar to a global snapshot.
+func newStatesPayload(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":1624958210,"states":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		if i%3 == 0 {
+			fmt.Fprintf(&buf, `["%06x",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]`, i)
+		} else {
+			fmt.Fprintf(&buf, `["%06x","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,false,17.95,117.3,-1.3,null,952.5,"0753",false,0]`, i)
+		}
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+func TestDecodeStatesStream(t *testing.T) {
+	payload := newStatesPayload(100)
+	// Results must be identical to the regular decoding
+	var rawResponse unstructuredStateResponse
+	assert.NoError(t, json.Unmarshal(payload, &rawResponse))
+	expected, err := parseStatesResponse(rawResponse, false)
+	assert.NoError(t, err)
+	var states []State
+	response, err := decodeStatesStream(bytes.NewReader(payload), false, func(state State) error {
+		states = append(states, state)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Time, response.Time)
+	assert.Nil(t, response.States)
+	assert.Equal(t, expected.States, states)
+}
+
+func TestDecodeStatesStreamCases(t *testing.T) {
+	type testCase struct {
+		payload        string
+		lenient        bool
+		expectedTime   time.Time
+		expectedStates int
+		expectedErrors int
+		expectedError  bool
+	}
+	valid := `["a50c7c",null,"United States",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0]`
+	invalid := `["a50c7c",null,"United States",null,1624891429,null,null,null,"invalid_on_ground",null,null,null,null,null,null,false,0]`
+	cases := []testCase{
+		// Regular response
+		{`{"time":1624958210,"states":[` + valid + `,` + valid + `]}`, false, time.Unix(1624958210, 0), 2, 0, false},
+		// Fields in any order, unknown fields are skipped
+		{`{"states":[` + valid + `],"foo":{"bar":[1,2]},"time":1624958210}`, false, time.Unix(1624958210, 0), 1, 0, false},
+		// Keys are case-insensitive
+		{`{"Time":1624958210,"States":[` + valid + `]}`, false, time.Unix(1624958210, 0), 1, 0, false},
+		{`{"TIME":1624958210,"sTaTeS":[` + valid + `]}`, false, time.Unix(1624958210, 0), 1, 0, false},
+		// Empty and null states
+		{`{"time":1624958210,"states":[]}`, false, time.Unix(1624958210, 0), 0, 0, false},
+		{`{"time":1624958210,"states":null}`, false, time.Unix(1624958210, 0), 0, 0, false},
+		{`{"time":null,"states":null}`, false, time.Unix(0, 0), 0, 0, false},
+		{`{}`, false, time.Unix(0, 0), 0, 0, false},
+		// Invalid state in strict mode
+		{`{"time":1624958210,"states":[` + valid + `,` + invalid + `]}`, false, time.Unix(1624958210, 0), 1, 0, true},
+		// Invalid state in lenient mode
+		{`{"time":1624958210,"states":[` + invalid + `,` + valid + `]}`, true, time.Unix(1624958210, 0), 1, 1, false},
+		// Malformed JSON
+		{`[]`, false, time.Unix(0, 0), 0, 0, true},
+		{`{"time":1624958210,"states":{}}`, false, time.Unix(1624958210, 0), 0, 0, true},
+		{`{"time":"now","states":[]}`, false, time.Unix(0, 0), 0, 0, true},
+		{`{"time":1624958210,"states":[` + valid, false, time.Unix(1624958210, 0), 1, 0, true},
+		{`{"time":1624958210,"states":[` + valid + `]`, false, time.Unix(1624958210, 0), 1, 0, true},
+	}
+	for _, c := range cases {
+		states := 0
+		response, err := decodeStatesStream(strings.NewReader(c.payload), c.lenient, func(state State) error {
+			states++
+			return nil
+		})
+		if c.expectedError {
+			assert.Error(t, err, c.payload)
+		} else {
+			assert.NoError(t, err, c.payload)
+		}
+		assert.Equal(t, c.expectedTime, response.Time, c.payload)
+		assert.Equal(t, c.expectedStates, states, c.payload)
+		assert.Len(t, response.Errors, c.expectedErrors, c.payload)
+	}
+}
+
+func TestStreamStates(t *testing.T) {
+	payload := newStatesPayload(10)
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write(payload)
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(100, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	bbox := &BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}
+	var icao24 []string
+	response, err := client.StreamStates(context.Background(), StatesQuery{Time: time.Unix(1624958210, 0), ICAO24: []string{"ae1fa7"}, BoundingBox: bbox}, func(state State) error {
+		icao24 = append(icao24, state.ICAO24)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1624958210, 0), response.Time)
+	assert.Len(t, icao24, 10)
+	assert.Equal(t, 1, budget.Used())
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "/states/all", requests[0].URL.Path)
+		assert.Equal(t, "1624958210", requests[0].URL.Query().Get("time"))
+		assert.Equal(t, "ae1fa7", requests[0].URL.Query().Get("icao24"))
+		assert.Equal(t, "45", requests[0].URL.Query().Get("lamin"))
+	}
+	// Callback errors abort decoding
+	errStop := errors.New("stop")
+	count := 0
+	_, err = client.StreamStates(context.Background(), StatesQuery{BoundingBox: bbox}, func(state State) error {
+		count++
+		if count == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.True(t, errors.Is(err, errStop))
+	assert.Equal(t, 3, count)
+}
+
+func TestStreamStatesQuery(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[["ae1fa7","TALON71 ","United States",1624891429,1624891429,-116.2121,43.5431,914.4,true,0,0,0,null,0,null,true,2,6]]}`))
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(100, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	// Extended states
+	var states []State
+	_, err := client.StreamStates(context.Background(), StatesQuery{Extended: true}, func(state State) error {
+		states = append(states, state)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, states, 1) {
+		assert.Equal(t, CategoryHeavy, states[0].Category)
+	}
+	// Own states are free
+	_, err = client.StreamStates(context.Background(), StatesQuery{Own: true, Serials: []int{1000}}, func(state State) error {
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, budget.Used())
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "/states/all", requests[0].URL.Path)
+		assert.Equal(t, "1", requests[0].URL.Query().Get("extended"))
+		assert.Equal(t, "/states/own", requests[1].URL.Path)
+		assert.Equal(t, "1000", requests[1].URL.Query().Get("serials"))
+	}
+}
+
+func TestStreamStatesAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(100, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	_, err := client.StreamStates(context.Background(), StatesQuery{}, func(state State) error {
+		t.Error("unexpected state")
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, 0, budget.Used())
+}
+
+func BenchmarkDecodeStatesResponse(b *testing.B) {
+	payload := newStatesPayload(10000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	for i := 0; i < b.N; i++ {
+		var rawResponse unstructuredStateResponse
+		_ = json.Unmarshal(payload, &rawResponse)
+		_, _ = parseStatesResponse(rawResponse, false)
+	}
+}
+
+func BenchmarkDecodeStatesStream(b *testing.B) {
+	payload := newStatesPayload(10000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	for i := 0; i < b.N; i++ {
+		_, _ = decodeStatesStream(bytes.NewReader(payload), false, func(state State) error {
+			return nil
+		})
+	}
+}
+
+func TestStreamStatesAntimeridian(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write(newStatesPayload(3))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	bbox := &BoundingBox{LatMin: -5, LonMin: 170, LatMax: 5, LonMax: -170}
+	var icao24 []string
+	response, err := client.StreamStates(context.Background(), StatesQuery{BoundingBox: bbox}, func(state State) error {
+		icao24 = append(icao24, state.ICAO24)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1624958210, 0), response.Time)
+	// Both sides report the same aircraft, which are only passed once
+	assert.Equal(t, []string{"000000", "000001", "000002"}, icao24)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "180", requests[0].URL.Query().Get("lomax"))
+		assert.Equal(t, "-180", requests[1].URL.Query().Get("lomin"))
+	}
+}
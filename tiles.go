@@ -0,0 +1,186 @@
+package opensky
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+const (
+	// Default tile size in degrees, such that every tile costs a single API credit.
+	defaultTileStep = 5.0
+	// Default number of tiles fetched in parallel.
+	defaultTileConcurrency = 4
+	// Tolerance in degrees for rounding errors, below which no further tile is added.
+	tileEpsilon = 1e-9
+)
+
+// Splits large areas into a grid of smaller bounding boxes, for GetStatesTiled.
+//
+// Zero values are replaced by sensible defaults.
+type Tiler struct {
+	LatStep     float64 // Maximum height of a tile in degrees. Defaults to 5.
+	LonStep     float64 // Maximum width of a tile in degrees. Defaults to 5.
+	Concurrency int     // Maximum number of tiles fetched in parallel. Defaults to 4.
+}
+
+// Returns a copy of the tiler, with all zero values replaced by defaults.
+func (t Tiler) withDefaults() Tiler {
+	if t.LatStep <= 0 {
+		t.LatStep = defaultTileStep
+	}
+	if t.LonStep <= 0 {
+		t.LonStep = defaultTileStep
+	}
+	if t.Concurrency < 1 {
+		t.Concurrency = defaultTileConcurrency
+	}
+	return t
+}
+
+// Splits the area into a grid of tiles, row by row from south-west to north-east.
+// Tiles at the northern and eastern edges are clipped to the area. Areas crossing the antimeridian
+// are split at 180° first, so that no tile crosses it.
+func (t Tiler) Tiles(area BoundingBox) (tiles []BoundingBox) {
+	t = t.withDefaults()
+	latMin, latMax := math.Min(area.LatMin, area.LatMax), math.Max(area.LatMin, area.LatMax)
+	for _, part := range area.Split() {
+		for _, lat := range steps(latMin, latMax, t.LatStep) {
+			for _, lon := range steps(part.LonMin, part.LonMax, t.LonStep) {
+				tiles = append(tiles, BoundingBox{LatMin: lat[0], LonMin: lon[0], LatMax: lat[1], LonMax: lon[1]})
+			}
+		}
+	}
+	return
+}
+
+// Returns the number of API credits that GetStatesTiled spends on the area, i.e. the sum of
+// the credit costs of all tiles.
+func (t Tiler) CreditCost(area BoundingBox) (cost int) {
+	for _, tile := range t.Tiles(area) {
+		tile := tile
+		cost += StatesCreditCost(&tile)
+	}
+	return
+}
+
+// Splits [min, max] into consecutive intervals of at most step. Returns a single interval, if min equals max.
+// Rounding errors do not result in an additional, degenerate interval at the end.
+func steps(min float64, max float64, step float64) (intervals [][2]float64) {
+	n := int(math.Ceil((max-min)/step - tileEpsilon))
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		end := min + float64(i+1)*step
+		if i == n-1 || end > max {
+			end = max
+		}
+		intervals = append(intervals, [2]float64{min + float64(i)*step, end})
+	}
+	return
+}
+
+// Error of a single tile fetched by GetStatesTiled.
+type TileError struct {
+	Tile BoundingBox // Bounding box of the failed tile.
+	Err  error       // Error of the query.
+}
+
+func (e *TileError) Error() string {
+	return fmt.Sprintf("tile %+v: %v", e.Tile, e.Err)
+}
+
+func (e *TileError) Unwrap() error {
+	return e.Err
+}
+
+// Error returned by GetStatesTiled, if some of the tiles failed.
+// It unwraps to the error of the first failed tile, so that errors.Is can be used with sentinel errors.
+type TilingError struct {
+	Tiles []*TileError // Errors of all failed tiles, in the order of the tiles.
+	Total int          // Total number of tiles.
+}
+
+func (e *TilingError) Error() string {
+	return fmt.Sprintf("opensky: %d of %d tiles failed, first error: %v", len(e.Tiles), e.Total, e.Tiles[0])
+}
+
+func (e *TilingError) Unwrap() error {
+	return e.Tiles[0]
+}
+
+// Retrieves state vectors for a large area, by splitting the bounding box of the query into tiles
+// and fetching them in parallel with QueryStates.
+//
+// A bounding box is required, since every tile is a separate request: with the default tiler,
+// the whole world takes 2592 requests. Tiler.CreditCost returns the credits spent on an area.
+//
+// The states of all tiles are merged, keeping the state with the most recent LastContact for
+// aircraft reported by several tiles. If some tiles fail, the states of the successful ones are
+// returned together with a *TilingError. Parse errors of lenient clients carry the tile they
+// belong to in their BoundingBox field.
+//
+// Once the context is done, no further tiles are fetched, and the states of the tiles fetched so
+// far are returned together with the error of the context.
+func (c *Client) GetStatesTiled(ctx context.Context, query StatesQuery, tiler Tiler) (response GetStatesResponse, err error) {
+	if query.BoundingBox == nil {
+		var v validator
+		v.addf("bbox", "required for tiled queries")
+		err = v.err()
+		return
+	}
+	if _, err = query.validate(); err != nil {
+		return
+	}
+	tiler = tiler.withDefaults()
+	tiles := tiler.Tiles(*query.BoundingBox)
+	responses := make([]GetStatesResponse, len(tiles))
+	tileErrors := make([]error, len(tiles))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < tiler.Concurrency && w < len(tiles); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				tileQuery := query
+				tileQuery.BoundingBox = &tiles[i]
+				responses[i], tileErrors[i] = c.QueryStates(ctx, tileQuery)
+				setErrorsBoundingBox(responses[i], tiles[i])
+			}
+		}()
+	}
+	// Stop dispatching tiles once the context is done
+dispatch:
+	for i := range tiles {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	// Merge successful tiles
+	var succeeded []GetStatesResponse
+	tilingErr := &TilingError{Total: len(tiles)}
+	for i, tileErr := range tileErrors {
+		if tileErr != nil {
+			tilingErr.Tiles = append(tilingErr.Tiles, &TileError{Tile: tiles[i], Err: tileErr})
+			continue
+		}
+		succeeded = append(succeeded, responses[i])
+	}
+	response = mergeStatesResponses(succeeded)
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	} else if len(tilingErr.Tiles) > 0 {
+		err = tilingErr
+	}
+	return
+}
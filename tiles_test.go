@@ -0,0 +1,215 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTilerTiles(t *testing.T) {
+	type testCase struct {
+		tiler         Tiler
+		area          BoundingBox
+		expectedTiles []BoundingBox
+	}
+	cases := []testCase{
+		// Area smaller than a tile
+		{Tiler{}, BoundingBox{LatMin: 45, LonMin: 5, LatMax: 47, LonMax: 8}, []BoundingBox{
+			{LatMin: 45, LonMin: 5, LatMax: 47, LonMax: 8},
+		}},
+		// Tiles are clipped at the edges
+		{Tiler{}, BoundingBox{LatMin: 40, LonMin: 0, LatMax: 47, LonMax: 10}, []BoundingBox{
+			{LatMin: 40, LonMin: 0, LatMax: 45, LonMax: 5},
+			{LatMin: 40, LonMin: 5, LatMax: 45, LonMax: 10},
+			{LatMin: 45, LonMin: 0, LatMax: 47, LonMax: 5},
+			{LatMin: 45, LonMin: 5, LatMax: 47, LonMax: 10},
+		}},
+		// Custom steps
+		{Tiler{LatStep: 10, LonStep: 20}, BoundingBox{LatMin: 40, LonMin: 0, LatMax: 50, LonMax: 30}, []BoundingBox{
+			{LatMin: 40, LonMin: 0, LatMax: 50, LonMax: 20},
+			{LatMin: 40, LonMin: 20, LatMax: 50, LonMax: 30},
+		}},
+		// Degenerate area
+		{Tiler{}, BoundingBox{LatMin: 45, LonMin: 5, LatMax: 45, LonMax: 5}, []BoundingBox{
+			{LatMin: 45, LonMin: 5, LatMax: 45, LonMax: 5},
+		}},
+		// Antimeridian
+		{Tiler{}, BoundingBox{LatMin: 0, LonMin: 175, LatMax: 5, LonMax: -175}, []BoundingBox{
+			{LatMin: 0, LonMin: 175, LatMax: 5, LonMax: 180},
+			{LatMin: 0, LonMin: -180, LatMax: 5, LonMax: -175},
+		}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expectedTiles, c.tiler.Tiles(c.area), "%+v", c.area)
+	}
+	// Rounding errors do not add degenerate tiles
+	type roundingCase struct {
+		tiler       Tiler
+		area        BoundingBox
+		expectedLen int
+	}
+	roundingCases := []roundingCase{
+		{Tiler{LatStep: 0.3}, BoundingBox{LatMin: 10, LonMin: 0, LatMax: 10.9, LonMax: 1}, 3},
+		{Tiler{LatStep: 0.7}, BoundingBox{LatMin: -1, LonMin: 0, LatMax: 1.1, LonMax: 1}, 3},
+	}
+	for _, c := range roundingCases {
+		tiles := c.tiler.Tiles(c.area)
+		if assert.Len(t, tiles, c.expectedLen, "%+v", c.area) {
+			assert.Equal(t, c.area.LatMin, tiles[0].LatMin)
+			assert.Equal(t, c.area.LatMax, tiles[len(tiles)-1].LatMax)
+		}
+		for _, tile := range tiles {
+			assert.Greater(t, tile.LatMax-tile.LatMin, c.tiler.LatStep/2, "%+v", tile)
+		}
+	}
+	// The whole world
+	world := BoundingBox{LatMin: -90, LonMin: -180, LatMax: 90, LonMax: 180}
+	tiles := Tiler{}.Tiles(world)
+	assert.Len(t, tiles, 36*72)
+	for _, tile := range tiles {
+		assert.Equal(t, 1, StatesCreditCost(&tile))
+	}
+	assert.Equal(t, 36*72, Tiler{}.CreditCost(world))
+}
+
+func TestTilerCreditCost(t *testing.T) {
+	type testCase struct {
+		tiler        Tiler
+		area         BoundingBox
+		expectedCost int
+	}
+	cases := []testCase{
+		{Tiler{}, BoundingBox{LatMin: 45, LonMin: 5, LatMax: 47, LonMax: 8}, 1},
+		{Tiler{}, BoundingBox{LatMin: 40, LonMin: 0, LatMax: 47, LonMax: 10}, 4},
+		{Tiler{}, BoundingBox{LatMin: 0, LonMin: 175, LatMax: 5, LonMax: -175}, 2},
+		// Tiles larger than 25 square degrees cost more
+		{Tiler{LatStep: 10, LonStep: 10}, BoundingBox{LatMin: 40, LonMin: 0, LatMax: 50, LonMax: 10}, StatesCreditCost(&BoundingBox{LatMin: 40, LonMin: 0, LatMax: 50, LonMax: 10})},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expectedCost, c.tiler.CreditCost(c.area), "%+v", c.area)
+	}
+}
+
+func TestGetStatesTiled(t *testing.T) {
+	var mu sync.Mutex
+	var active, maxActive int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		// Every tile reports its own aircraft and a shared one, which is freshest in the eastern tile
+		lomin, _ := strconv.ParseFloat(r.URL.Query().Get("lomin"), 64)
+		if lomin == 10 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"time":1624958210,"states":[["%06x",null,"A",null,1624891429,null,null,null,false,null,null,null,null,null,null,false,0],["ffffff",null,"A",null,%d,null,null,null,false,null,null,null,null,null,null,false,0]]}`,
+			int(lomin), 1624891400+int(lomin))
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(100, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	query := StatesQuery{BoundingBox: &BoundingBox{LatMin: 45, LonMin: 0, LatMax: 50, LonMax: 20}}
+	response, err := client.GetStatesTiled(context.Background(), query, Tiler{Concurrency: 2})
+	// The failed tile is reported, but the other tiles are kept
+	var tilingErr *TilingError
+	if assert.True(t, errors.As(err, &tilingErr)) {
+		assert.Equal(t, 4, tilingErr.Total)
+		if assert.Len(t, tilingErr.Tiles, 1) {
+			assert.Equal(t, BoundingBox{LatMin: 45, LonMin: 10, LatMax: 50, LonMax: 15}, tilingErr.Tiles[0].Tile)
+		}
+	}
+	assert.True(t, errors.Is(err, ErrServer))
+	assert.Equal(t, time.Unix(1624958210, 0), response.Time)
+	var icao24 []string
+	for _, state := range response.States {
+		icao24 = append(icao24, state.ICAO24)
+		if state.ICAO24 == "ffffff" {
+			assert.Equal(t, newUnixTime(1624891415), state.LastContact)
+		}
+	}
+	assert.Equal(t, []string{"000000", "ffffff", "000005", "00000f"}, icao24)
+	assert.LessOrEqual(t, maxActive, 2)
+	assert.Equal(t, 3, budget.Used())
+}
+
+func TestGetStatesTiledCancel(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		// Cancel while the first tiles are fetched
+		cancel()
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[]}`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	world := &BoundingBox{LatMin: -90, LonMin: -180, LatMax: 90, LonMax: 180}
+	_, err := client.GetStatesTiled(ctx, StatesQuery{BoundingBox: world}, Tiler{Concurrency: 2})
+	assert.True(t, errors.Is(err, context.Canceled))
+	var tilingErr *TilingError
+	assert.False(t, errors.As(err, &tilingErr))
+	mu.Lock()
+	assert.LessOrEqual(t, requests, 2)
+	mu.Unlock()
+	// An already cancelled context fetches no tiles at all
+	unused := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %v", r.URL)
+	}))
+	defer unused.Close()
+	client = NewClient("", "", WithBaseURL(unused.URL))
+	_, err = client.GetStatesTiled(ctx, StatesQuery{BoundingBox: world}, Tiler{})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestGetStatesTiledLenient(t *testing.T) {
+	// Every tile reports an invalid state at the same position
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":[["a50c7c",null,"United States",null,1624891429,null,null,null,"x",null,null,null,null,null,null,false,0]]}`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL), WithLenientParsing())
+	query := StatesQuery{BoundingBox: &BoundingBox{LatMin: 45, LonMin: 0, LatMax: 50, LonMax: 10}}
+	response, err := client.GetStatesTiled(context.Background(), query, Tiler{Concurrency: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Errors, 2) {
+		assert.Equal(t, &BoundingBox{LatMin: 45, LonMin: 0, LatMax: 50, LonMax: 5}, response.Errors[0].BoundingBox)
+		assert.Equal(t, &BoundingBox{LatMin: 45, LonMin: 5, LatMax: 50, LonMax: 10}, response.Errors[1].BoundingBox)
+	}
+}
+
+func TestGetStatesTiledValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %v", r.URL)
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	var validationErr *ValidationError
+	// The bounding box is required
+	_, err := client.GetStatesTiled(context.Background(), StatesQuery{}, Tiler{})
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Equal(t, []ValidationProblem{{Parameter: "bbox", Message: "required for tiled queries"}}, validationErr.Problems)
+	}
+	_, err = client.GetStatesTiled(context.Background(), StatesQuery{Own: true}, Tiler{})
+	assert.True(t, errors.As(err, &validationErr))
+	_, err = client.GetStatesTiled(context.Background(), StatesQuery{BoundingBox: &BoundingBox{LatMin: 100}}, Tiler{})
+	assert.True(t, errors.As(err, &validationErr))
+}
@@ -5,8 +5,11 @@ import (
 	"time"
 )
 
-// Utility time wrapper struct, needed for unmarshaling unix
-// timestamps from JSON objects.
+// Utility time wrapper struct, needed for marshaling and unmarshaling unix
+// timestamps in JSON objects.
+//
+// The zero value is encoded as JSON null, or as empty text, so that it round-trips
+// with the null values sent by the API.
 type UnixTime struct {
 	time.Time
 }
@@ -21,6 +24,15 @@ func newUnixTimeP(sec int64) *UnixTime {
 	return &UnixTime{time.Unix(sec, 0)}
 }
 
+// Encodes the time as unix timestamp, or as null if the time is zero.
+func (t UnixTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return strconv.AppendInt(nil, t.Unix(), 10), nil
+}
+
+// Decodes a unix timestamp. Null is decoded as the zero time.
 func (t *UnixTime) UnmarshalJSON(s []byte) error {
 	raw := string(s)
 	if raw == "null" {
@@ -34,3 +46,25 @@ func (t *UnixTime) UnmarshalJSON(s []byte) error {
 	*t = UnixTime{time.Unix(unixTimestamp, 0)}
 	return nil
 }
+
+// Encodes the time as unix timestamp, or as empty text if the time is zero.
+func (t UnixTime) MarshalText() ([]byte, error) {
+	if t.IsZero() {
+		return []byte{}, nil
+	}
+	return strconv.AppendInt(nil, t.Unix(), 10), nil
+}
+
+// Decodes a unix timestamp. Empty text is decoded as the zero time.
+func (t *UnixTime) UnmarshalText(s []byte) error {
+	if len(s) == 0 {
+		*t = UnixTime{time.Time{}}
+		return nil
+	}
+	unixTimestamp, err := strconv.ParseInt(string(s), 10, 64)
+	if err != nil {
+		return err
+	}
+	*t = UnixTime{time.Unix(unixTimestamp, 0)}
+	return nil
+}
@@ -0,0 +1,138 @@
+package opensky
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Represents a single waypoint of a Track.
+//
+// All pointer fields are nullable, therefore checks are required, before accessing those fields.
+type Waypoint struct {
+	Time               UnixTime `json:"time"`                    // Time at which the waypoint was recorded.
+	Latitude           *float64 `json:"latitude,omitempty"`      // In ellipsoidal coordinates (WGS-84) and degrees. Can be nil.
+	Longitude          *float64 `json:"longitude,omitempty"`     // In ellipsoidal coordinates (WGS-84) and degrees. Can be nil.
+	BarometricAltitude *float64 `json:"baro_altitude,omitempty"` // Barometric altitude in meters. Can be nil.
+	Heading            *float64 `json:"true_track,omitempty"`    // True track in decimal degrees (0 is north). Can be nil.
+	OnGround           bool     `json:"on_ground"`               // True if aircraft is on ground (sends ADS-B surface position reports).
+}
+
+// Represents the trajectory of an aircraft, as a sequence of waypoints.
+type Track struct {
+	ICAO24    string     `json:"icao24"`             // ICAO24 address of the transmitter in hex string representation.
+	CallSign  string     `json:"callsign,omitempty"` // CallSign of the vehicle. Can be empty if no callsign has been received.
+	StartTime UnixTime   `json:"startTime"`          // Time of the first waypoint.
+	EndTime   UnixTime   `json:"endTime"`            // Time of the last waypoint.
+	Path      []Waypoint `json:"path"`               // Waypoints of the trajectory, in chronological order.
+}
+
+// Unstructured raw response for track queries.
+type unstructuredTrackResponse struct {
+	ICAO24    string          `json:"icao24"`
+	CallSign  *string         `json:"callsign"`
+	StartTime float64         `json:"startTime"`
+	EndTime   float64         `json:"endTime"`
+	Path      [][]interface{} `json:"path"`
+}
+
+// Retrieves the trajectory for a certain aircraft, identified by the icao24 address parameter,
+// at a given time.
+//
+// If time.Time{} is passed as a parameter, then the live track is returned, if there is any
+// flight ongoing for the given aircraft.
+//
+// If no track was found for the given aircraft and time, an error matching ErrNotFound will be
+// returned instead.
+func (c *Client) GetTrack(icao24 string, time time.Time) (track Track, err error) {
+	return c.GetTrackContext(context.Background(), icao24, time)
+}
+
+// Same as GetTrack, but the passed context controls cancellation and deadline of the request.
+func (c *Client) GetTrackContext(ctx context.Context, icao24 string, time time.Time) (track Track, err error) {
+	var v validator
+	icao24 = v.icao24(icao24)
+	if err = v.err(); err != nil {
+		return
+	}
+	request, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/tracks/all", c.baseURL))
+	if err != nil {
+		return
+	}
+	q := request.URL.Query()
+	q.Set("icao24", icao24)
+	if time.IsZero() {
+		q.Set("time", "0")
+	} else {
+		q.Set("time", fmt.Sprintf("%v", time.Unix()))
+	}
+	request.URL.RawQuery = q.Encode()
+	// Fetch response
+	var rawResponse unstructuredTrackResponse
+	err = c.doHTTP(request, &rawResponse)
+	if err != nil {
+		return
+	}
+	return parseTrackResponse(rawResponse)
+}
+
+// Parse a single waypoint array from an unstructured track response.
+// The i parameter represents the index of the waypoint element in the path.
+func parseWaypoint(w []interface{}, i int) (waypoint Waypoint, err error) {
+	if len(w) < 6 {
+		err = fmt.Errorf("invalid waypoint object at position %v: response contains %v values, expected 6", i, len(w))
+		return
+	}
+	// time
+	var rawTime int64
+	rawTime, err = jsonNumberToInt(w[0])
+	if err != nil {
+		err = fmt.Errorf("invalid time value at position %d: %w", i, err)
+		return
+	}
+	// latitude
+	lat := jsonNumberToFloat(w[1])
+	// longitude
+	lon := jsonNumberToFloat(w[2])
+	// baro_altitude
+	baroAltitude := jsonNumberToFloat(w[3])
+	// true_track
+	trueTrack := jsonNumberToFloat(w[4])
+	// on_ground
+	onGround, ok := w[5].(bool)
+	if !ok {
+		err = fmt.Errorf("invalid on_ground value at position %d: %v", i, w[5])
+		return
+	}
+	// Set waypoint values
+	waypoint = Waypoint{
+		Time:               newUnixTime(rawTime),
+		Latitude:           lat,
+		Longitude:          lon,
+		BarometricAltitude: baroAltitude,
+		Heading:            trueTrack,
+		OnGround:           onGround,
+	}
+	return
+}
+
+// Parses an unstructured track response.
+func parseTrackResponse(rawResponse unstructuredTrackResponse) (track Track, err error) {
+	track.ICAO24 = rawResponse.ICAO24
+	if rawResponse.CallSign != nil {
+		track.CallSign = *rawResponse.CallSign
+	}
+	track.StartTime = newUnixTime(int64(rawResponse.StartTime))
+	track.EndTime = newUnixTime(int64(rawResponse.EndTime))
+	// Parse waypoints
+	for i, w := range rawResponse.Path {
+		var waypoint Waypoint
+		waypoint, err = parseWaypoint(w, i)
+		if err != nil {
+			return
+		}
+		// Add waypoint
+		track.Path = append(track.Path, waypoint)
+	}
+	return
+}
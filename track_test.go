@@ -0,0 +1,124 @@
+package opensky
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWaypoint(t *testing.T) {
+	type testCase struct {
+		raw            []interface{}
+		expectedResult Waypoint
+		expectedError  bool
+	}
+	cases := []testCase{
+		{
+			// All optional values are filled -> OK
+			[]interface{}{float64(1624891429), 43.5431, -116.2121, 914.4, 117.3, false},
+			Waypoint{
+				Time:               newUnixTime(1624891429),
+				Latitude:           newFloat(43.5431),
+				Longitude:          newFloat(-116.2121),
+				BarometricAltitude: newFloat(914.4),
+				Heading:            newFloat(117.3),
+				OnGround:           false,
+			},
+			false,
+		},
+		{
+			// All optional values are nil -> OK
+			[]interface{}{float64(1624891429), nil, nil, nil, nil, true},
+			Waypoint{
+				Time:     newUnixTime(1624891429),
+				OnGround: true,
+			},
+			false,
+		},
+		{
+			// Optional values are invalid -> ignored -> OK
+			[]interface{}{float64(1624891429), "invalid_lat", "invalid_lon", "invalid_baro_altitude", "invalid_true_track", true},
+			Waypoint{
+				Time:     newUnixTime(1624891429),
+				OnGround: true,
+			},
+			false,
+		},
+		{
+			// time is invalid -> Error
+			[]interface{}{"invalid_time", nil, nil, nil, nil, true},
+			Waypoint{},
+			true,
+		},
+		{
+			// on_ground is invalid -> Error
+			[]interface{}{float64(1624891429), nil, nil, nil, nil, 666},
+			Waypoint{},
+			true,
+		},
+		{
+			// Too few values -> Error
+			[]interface{}{float64(1624891429), nil, nil, nil, nil},
+			Waypoint{},
+			true,
+		},
+	}
+	for i, c := range cases {
+		waypoint, err := parseWaypoint(c.raw, i)
+		assert.Equal(t, c.expectedResult, waypoint)
+		if c.expectedError {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestGetTrack(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(`{"icao24":"3c4b26","callsign":"DLH9LF  ","startTime":1624891000,"endTime":1624891429,"path":[[1624891000,50.0379,8.5622,null,249,true],[1624891429,50.1,8.4,1219.2,251.5,false]]}`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	// Live track
+	track, err := client.GetTrack("3c4b26", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, Track{
+		ICAO24:    "3c4b26",
+		CallSign:  "DLH9LF  ",
+		StartTime: newUnixTime(1624891000),
+		EndTime:   newUnixTime(1624891429),
+		Path: []Waypoint{
+			{
+				Time:      newUnixTime(1624891000),
+				Latitude:  newFloat(50.0379),
+				Longitude: newFloat(8.5622),
+				Heading:   newFloat(249),
+				OnGround:  true,
+			},
+			{
+				Time:               newUnixTime(1624891429),
+				Latitude:           newFloat(50.1),
+				Longitude:          newFloat(8.4),
+				BarometricAltitude: newFloat(1219.2),
+				Heading:            newFloat(251.5),
+				OnGround:           false,
+			},
+		},
+	}, track)
+	// Historical track
+	_, err = client.GetTrack("3c4b26", time.Unix(1624891200, 0))
+	assert.NoError(t, err)
+	// Check rendered requests
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "/tracks/all", requests[0].URL.Path)
+		assert.Equal(t, "3c4b26", requests[0].URL.Query().Get("icao24"))
+		assert.Equal(t, "0", requests[0].URL.Query().Get("time"))
+		assert.Equal(t, "1624891200", requests[1].URL.Query().Get("time"))
+	}
+}
@@ -0,0 +1,151 @@
+package opensky
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Describes a single invalid query parameter.
+type ValidationProblem struct {
+	Parameter string // Name of the parameter, e.g. icao24, bbox or begin.
+	Message   string // Description of the problem.
+}
+
+func (p ValidationProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Parameter, p.Message)
+}
+
+// Error returned by Client methods when query parameters are invalid.
+// Parameters are validated before any request is sent, so that no API credits are spent.
+// Every problem found is listed, not only the first one.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		problems[i] = p.String()
+	}
+	return fmt.Sprintf("opensky: invalid parameters: %s", strings.Join(problems, "; "))
+}
+
+// Collects the problems of the parameters of a single query.
+type validator struct {
+	problems []ValidationProblem
+}
+
+// Records a problem with the passed parameter.
+func (v *validator) addf(parameter string, format string, args ...interface{}) {
+	v.problems = append(v.problems, ValidationProblem{Parameter: parameter, Message: fmt.Sprintf(format, args...)})
+}
+
+// Returns a *ValidationError listing all recorded problems, or nil if there are none.
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// Checks that all addresses are 6-digit hex strings, and returns them in lowercase.
+// The passed slice is not modified.
+func (v *validator) icao24List(addresses []string) []string {
+	if len(addresses) == 0 {
+		return addresses
+	}
+	normalized := make([]string, len(addresses))
+	for i, address := range addresses {
+		normalized[i] = v.icao24(address)
+	}
+	return normalized
+}
+
+// Checks that the address is a 6-digit hex string, and returns it in lowercase.
+func (v *validator) icao24(address string) string {
+	normalized := strings.ToLower(address)
+	if !isICAO24(normalized) {
+		v.addf("icao24", "%q is not a 6-digit hex address", address)
+	}
+	return normalized
+}
+
+// Reports whether the lowercase address is a 6-digit hex string.
+func isICAO24(address string) bool {
+	if len(address) != 6 {
+		return false
+	}
+	for _, r := range address {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks that the coordinates of the bounding box are within range, and that the minimum latitude
+// does not exceed the maximum latitude. Boxes crossing the antimeridian are valid.
+// A nil bounding box is valid.
+func (v *validator) boundingBox(bbox *BoundingBox) {
+	if bbox == nil {
+		return
+	}
+	v.latitude("lamin", bbox.LatMin)
+	v.latitude("lamax", bbox.LatMax)
+	v.longitude("lomin", bbox.LonMin)
+	v.longitude("lomax", bbox.LonMax)
+	if bbox.LatMin > bbox.LatMax {
+		v.addf("bbox", "minimum latitude %v exceeds maximum latitude %v", bbox.LatMin, bbox.LatMax)
+	}
+}
+
+// Checks that the latitude is within [-90, 90].
+func (v *validator) latitude(parameter string, lat float64) {
+	if !(lat >= -90 && lat <= 90) {
+		v.addf(parameter, "latitude %v is not within [-90, 90]", lat)
+	}
+}
+
+// Checks that the longitude is within [-180, 180].
+func (v *validator) longitude(parameter string, lon float64) {
+	if !(lon >= -180 && lon <= 180) {
+		v.addf(parameter, "longitude %v is not within [-180, 180]", lon)
+	}
+}
+
+// Checks that begin is before end and, if max is positive, that the interval does not exceed max.
+// If the interval is not required, begin and end may be omitted by passing zero values.
+func (v *validator) interval(begin time.Time, end time.Time, max time.Duration, required bool) {
+	if begin.IsZero() || end.IsZero() {
+		if required {
+			if begin.IsZero() {
+				v.addf("begin", "required")
+			}
+			if end.IsZero() {
+				v.addf("end", "required")
+			}
+		}
+		return
+	}
+	if !begin.Before(end) {
+		v.addf("begin", "%v is not before end %v", begin.Unix(), end.Unix())
+	} else if max > 0 && end.Sub(begin) > max {
+		v.addf("end", "interval of %v exceeds the maximum of %v", end.Sub(begin), max)
+	}
+}
+
+// Checks that the airport is a 4-character ICAO code, and returns it in uppercase.
+func (v *validator) airport(airport string) string {
+	normalized := strings.ToUpper(airport)
+	valid := len(normalized) == 4
+	for _, r := range normalized {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			valid = false
+		}
+	}
+	if !valid {
+		v.addf("airport", "%q is not a 4-character ICAO code", airport)
+	}
+	return normalized
+}
@@ -0,0 +1,145 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	begin := time.Unix(1624860000, 0)
+	type testCase struct {
+		name               string
+		validate           func(v *validator)
+		expectedParameters []string
+	}
+	cases := []testCase{
+		{"valid icao24", func(v *validator) { v.icao24List([]string{"a50c7c", "AE1FA7", "000000"}) }, nil},
+		{"invalid icao24", func(v *validator) { v.icao24List([]string{"a50c7", "a50c7cc", "g50c7c", "", " a50c7c"}) }, []string{"icao24", "icao24", "icao24", "icao24", "icao24"}},
+		{"single icao24", func(v *validator) { v.icao24("3c644") }, []string{"icao24"}},
+		{"nil bbox", func(v *validator) { v.boundingBox(nil) }, nil},
+		{"valid bbox", func(v *validator) { v.boundingBox(&BoundingBox{LatMin: -90, LonMin: -180, LatMax: 90, LonMax: 180}) }, nil},
+		{"point bbox", func(v *validator) { v.boundingBox(&BoundingBox{LatMin: 45, LonMin: 5, LatMax: 45, LonMax: 5}) }, nil},
+		{"out of range bbox", func(v *validator) { v.boundingBox(&BoundingBox{LatMin: -91, LonMin: -181, LatMax: 91, LonMax: 181}) }, []string{"lamin", "lamax", "lomin", "lomax"}},
+		{"inverted bbox", func(v *validator) { v.boundingBox(&BoundingBox{LatMin: 50, LonMin: 5, LatMax: 45, LonMax: 10}) }, []string{"bbox"}},
+		{"antimeridian bbox", func(v *validator) { v.boundingBox(&BoundingBox{LatMin: 45, LonMin: 170, LatMax: 50, LonMax: -170}) }, nil},
+		{"valid interval", func(v *validator) { v.interval(begin, begin.Add(time.Hour), 2*time.Hour, true) }, nil},
+		{"optional interval", func(v *validator) { v.interval(time.Time{}, time.Time{}, 0, false) }, nil},
+		{"missing interval", func(v *validator) { v.interval(time.Time{}, time.Time{}, 0, true) }, []string{"begin", "end"}},
+		{"empty interval", func(v *validator) { v.interval(begin, begin, 0, false) }, []string{"begin"}},
+		{"inverted interval", func(v *validator) { v.interval(begin, begin.Add(-time.Hour), 0, false) }, []string{"begin"}},
+		{"long interval", func(v *validator) { v.interval(begin, begin.Add(3*time.Hour), 2*time.Hour, true) }, []string{"end"}},
+		{"valid airport", func(v *validator) { v.airport("eddf") }, nil},
+		{"invalid airport", func(v *validator) { v.airport("ED-F") }, []string{"airport"}},
+	}
+	for _, c := range cases {
+		var v validator
+		c.validate(&v)
+		var parameters []string
+		for _, p := range v.problems {
+			parameters = append(parameters, p.Parameter)
+		}
+		assert.Equal(t, c.expectedParameters, parameters, c.name)
+		if c.expectedParameters == nil {
+			assert.NoError(t, v.err(), c.name)
+		} else {
+			var validationErr *ValidationError
+			assert.True(t, errors.As(v.err(), &validationErr), c.name)
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{Problems: []ValidationProblem{
+		{Parameter: "icao24", Message: `"xyz" is not a 6-digit hex address`},
+		{Parameter: "lamin", Message: "latitude 100 is not within [-90, 90]"},
+	}}
+	assert.Equal(t, `opensky: invalid parameters: icao24: "xyz" is not a 6-digit hex address; lamin: latitude 100 is not within [-90, 90]`, err.Error())
+}
+
+func TestClientValidation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %v", r.URL)
+	}))
+	defer server.Close()
+	budget := NewCreditBudget(100, nil)
+	client := NewClient("", "", WithBaseURL(server.URL), WithCreditBudget(budget))
+	begin := time.Unix(1624860000, 0)
+	// All problems are reported at once
+	_, err := client.GetStates(time.Time{}, []string{"a50c7c", "xyz"}, &BoundingBox{LatMin: 50, LonMin: 5, LatMax: 45, LonMax: 200})
+	var validationErr *ValidationError
+	if assert.True(t, errors.As(err, &validationErr)) {
+		assert.Len(t, validationErr.Problems, 3)
+	}
+	assert.Equal(t, 0, budget.Used())
+	calls := map[string]func() error{
+		"GetExtendedStates": func() error {
+			_, err := client.GetExtendedStates(time.Time{}, []string{"xyz"}, nil)
+			return err
+		},
+		"GetOwnStates": func() error {
+			_, err := client.GetOwnStates(time.Time{}, []string{"xyz"}, nil)
+			return err
+		},
+		"StreamStates": func() error {
+			_, err := client.StreamStates(context.Background(), StatesQuery{BoundingBox: &BoundingBox{LatMin: 91}}, func(state State) error {
+				return nil
+			})
+			return err
+		},
+		"GetFlights": func() error {
+			_, err := client.GetFlights(begin, begin)
+			return err
+		},
+		"GetFlightsByAircraft": func() error {
+			_, err := client.GetFlightsByAircraft("", begin, begin.Add(time.Hour))
+			return err
+		},
+		"GetFlightsRange": func() error {
+			_, err := client.GetFlightsRange(time.Time{}, begin, 1)
+			return err
+		},
+		"GetFlightsByAircraftRange": func() error {
+			_, err := client.GetFlightsByAircraftRange("xyz", begin, begin.Add(time.Hour), 1)
+			return err
+		},
+		"GetArrivalsByAirport": func() error {
+			_, err := client.GetArrivalsByAirport("ED", begin, begin.Add(time.Hour))
+			return err
+		},
+		"GetTrack": func() error {
+			_, err := client.GetTrack("a50c7", time.Time{})
+			return err
+		},
+	}
+	for name, call := range calls {
+		err := call()
+		assert.True(t, errors.As(err, &validationErr), name)
+	}
+}
+
+func TestICAO24Normalization(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		_, _ = w.Write([]byte(`{"time":1624958210,"states":null}`))
+	}))
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	icao24 := []string{"AE1FA7", "a50c7c"}
+	_, err := client.GetStates(time.Time{}, icao24, nil)
+	assert.NoError(t, err)
+	_, err = client.GetOwnStates(time.Time{}, icao24, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "ae1fa7,a50c7c", requests[0].URL.Query().Get("icao24"))
+		assert.Equal(t, "ae1fa7,a50c7c", requests[1].URL.Query().Get("icao24"))
+	}
+	// The passed slice is not modified
+	assert.Equal(t, []string{"AE1FA7", "a50c7c"}, icao24)
+}
@@ -0,0 +1,108 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// Minimum interval between polls of anonymous clients, matching the time resolution of OpenSky.
+	minWatchIntervalAnonymous = 10 * time.Second
+	// Minimum interval between polls of authenticated clients, matching the time resolution of OpenSky.
+	minWatchIntervalAuthenticated = 5 * time.Second
+)
+
+// Configuration for polling state vectors continuously with Client.Watch.
+//
+// Zero values are replaced by sensible defaults.
+type Watcher struct {
+	Query    StatesQuery   // Query executed by every poll, e.g. with Own set for the states of your own sensors.
+	Interval time.Duration // Delay between polls. Raised to 10 seconds for anonymous and 5 seconds for authenticated clients, if lower.
+
+	OnError func(err error)                                  // Optional hook, invoked for every failed poll. Polling continues afterwards.
+	Sleep   func(ctx context.Context, d time.Duration) error // Waits between polls. Defaults to a timer, which is interrupted when ctx is done.
+}
+
+// Returns a copy of the watcher, with all zero values replaced by defaults.
+func (w Watcher) withDefaults(authenticated bool) Watcher {
+	min := minWatchIntervalAnonymous
+	if authenticated {
+		min = minWatchIntervalAuthenticated
+	}
+	if w.Interval < min {
+		w.Interval = min
+	}
+	if w.Sleep == nil {
+		w.Sleep = sleepContext
+	}
+	return w
+}
+
+// Polls state vectors according to the watcher, and passes every new snapshot to fn, until the
+// context is done or fn returns an error.
+//
+// Snapshots with the same time as the previous one are skipped. Failed polls are reported to the
+// OnError hook of the watcher and retried after the interval; if the API asks to retry later, e.g.
+// because the credits are exhausted, the next poll is delayed accordingly.
+//
+// Returns the error of fn, or the error of the context once it is done. An invalid query is
+// returned immediately.
+func (c *Client) Watch(ctx context.Context, watcher Watcher, fn func(response GetStatesResponse) error) error {
+	if _, err := watcher.Query.validate(); err != nil {
+		return err
+	}
+	watcher = watcher.withDefaults(c.auth != nil)
+	var last time.Time
+	for {
+		response, err := c.QueryStates(ctx, watcher.Query)
+		wait := watcher.Interval
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			if watcher.OnError != nil {
+				watcher.OnError(err)
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.RateLimit.RetryAfter > wait {
+				wait = apiErr.RateLimit.RetryAfter
+			}
+		case !response.Time.Equal(last):
+			last = response.Time
+			if err = fn(response); err != nil {
+				return err
+			}
+		}
+		// Back off until the credits are available again
+		if rateLimit := c.LastRateLimit(); rateLimit.Remaining != nil && *rateLimit.Remaining <= 0 && rateLimit.RetryAfter > wait {
+			wait = rateLimit.RetryAfter
+		}
+		if err = watcher.Sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Polls state vectors like Watch, but delivers the snapshots on the returned channel.
+// The channel is closed once the context is done. An invalid query is returned as error before
+// polling starts, without a channel.
+func (c *Client) WatchChannel(ctx context.Context, watcher Watcher) (<-chan GetStatesResponse, error) {
+	if _, err := watcher.Query.validate(); err != nil {
+		return nil, err
+	}
+	responses := make(chan GetStatesResponse)
+	go func() {
+		defer close(responses)
+		// Since the query is valid, watching only ends once the context is done
+		_ = c.Watch(ctx, watcher, func(response GetStatesResponse) error {
+			select {
+			case responses <- response:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return responses, nil
+}
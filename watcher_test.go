@@ -0,0 +1,160 @@
+package opensky
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a server, which responds with the passed snapshot times in order, repeating the last one.
+// A zero time responds with 429 and a Retry-After of one minute.
+func newWatchServer(times ...int64) *httptest.Server {
+	var mu sync.Mutex
+	i := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		t := times[i]
+		if i < len(times)-1 {
+			i++
+		}
+		mu.Unlock()
+		if t == 0 {
+			w.Header().Set("X-Rate-Limit-Retry-After-Seconds", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintf(w, `{"time":%d,"states":null}`, t)
+	}))
+}
+
+// Records the delays of a watcher, and cancels the context after n waits.
+type watchSleeper struct {
+	cancel context.CancelFunc
+	n      int
+	delays []time.Duration
+}
+
+func (s *watchSleeper) sleep(ctx context.Context, d time.Duration) error {
+	s.delays = append(s.delays, d)
+	if len(s.delays) >= s.n {
+		s.cancel()
+	}
+	return ctx.Err()
+}
+
+func TestWatch(t *testing.T) {
+	server := newWatchServer(1000, 1000, 1010, 0, 1020)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sleeper := &watchSleeper{cancel: cancel, n: 5}
+	var errs []error
+	var times []int64
+	client := NewClient("", "", WithBaseURL(server.URL))
+	err := client.Watch(ctx, Watcher{
+		Interval: 15 * time.Second,
+		OnError:  func(err error) { errs = append(errs, err) },
+		Sleep:    sleeper.sleep,
+	}, func(response GetStatesResponse) error {
+		times = append(times, response.Time.Unix())
+		return nil
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	// Unchanged snapshots are skipped
+	assert.Equal(t, []int64{1000, 1010, 1020}, times)
+	if assert.Len(t, errs, 1) {
+		assert.True(t, errors.Is(errs[0], ErrRateLimited))
+	}
+	// Rate limited polls are delayed by the requested time
+	assert.Equal(t, []time.Duration{15 * time.Second, 15 * time.Second, 15 * time.Second, time.Minute, 15 * time.Second}, sleeper.delays)
+}
+
+func TestWatchMinimumInterval(t *testing.T) {
+	server := newWatchServer(1000)
+	defer server.Close()
+	type testCase struct {
+		client        *Client
+		interval      time.Duration
+		expectedDelay time.Duration
+	}
+	cases := []testCase{
+		{NewClient("", "", WithBaseURL(server.URL)), 0, 10 * time.Second},
+		{NewClient("", "", WithBaseURL(server.URL)), time.Second, 10 * time.Second},
+		{NewClient("", "", WithBaseURL(server.URL)), time.Minute, time.Minute},
+		{NewClient("user", "password", WithBaseURL(server.URL)), time.Second, 5 * time.Second},
+		{NewClient("user", "password", WithBaseURL(server.URL)), 7 * time.Second, 7 * time.Second},
+	}
+	for _, c := range cases {
+		ctx, cancel := context.WithCancel(context.Background())
+		sleeper := &watchSleeper{cancel: cancel, n: 1}
+		err := c.client.Watch(ctx, Watcher{Interval: c.interval, Sleep: sleeper.sleep}, func(response GetStatesResponse) error {
+			return nil
+		})
+		cancel()
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, []time.Duration{c.expectedDelay}, sleeper.delays, "%v", c.interval)
+	}
+}
+
+func TestWatchStop(t *testing.T) {
+	server := newWatchServer(1000, 1010)
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	// Errors of the callback stop watching
+	errStop := errors.New("stop")
+	polls := 0
+	err := client.Watch(context.Background(), Watcher{Sleep: func(ctx context.Context, d time.Duration) error {
+		return nil
+	}}, func(response GetStatesResponse) error {
+		polls++
+		if polls == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.True(t, errors.Is(err, errStop))
+	assert.Equal(t, 2, polls)
+	// Invalid queries are returned immediately
+	err = client.Watch(context.Background(), Watcher{Query: StatesQuery{ICAO24: []string{"xyz"}}}, func(response GetStatesResponse) error {
+		t.Error("unexpected response")
+		return nil
+	})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
+
+func TestWatchChannel(t *testing.T) {
+	server := newWatchServer(1000, 1010, 1020)
+	defer server.Close()
+	client := NewClient("", "", WithBaseURL(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	responses, err := client.WatchChannel(ctx, Watcher{Sleep: func(ctx context.Context, d time.Duration) error {
+		return ctx.Err()
+	}})
+	assert.NoError(t, err)
+	var times []int64
+	for response := range responses {
+		times = append(times, response.Time.Unix())
+		if len(times) == 3 {
+			cancel()
+		}
+	}
+	// The channel is closed after cancellation
+	assert.Equal(t, []int64{1000, 1010, 1020}, times)
+
+	// Invalid queries are returned before polling starts
+	responses, err = client.WatchChannel(context.Background(), Watcher{
+		Query: StatesQuery{ICAO24: []string{"xyz"}},
+	})
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Nil(t, responses)
+}